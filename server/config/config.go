@@ -1,9 +1,12 @@
 package config
 
 import (
+	"github.com/joho/godotenv"
 	"log"
 	"os"
-	"github.com/joho/godotenv"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -13,10 +16,61 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	DBSSLMode  string
+
+	// WebSocket heartbeat and limits
+	WSPingInterval   time.Duration // How often the server pings each connection
+	WSPongWait       time.Duration // How long a connection may stay silent before it is reaped
+	WSWriteWait      time.Duration // Deadline for writing a single frame
+	WSMaxMessageSize int64         // Largest inbound frame in bytes
+	WSResumeMaxGap   int           // Most missed messages replayed on reconnect before a REST refetch is required
+
+	// Broadcaster selects how room events reach other server instances: "memory" or "postgres"
+	Broadcaster string
+
+	// Attachment storage: "local" keeps files under StorageLocalDir, "s3" uses the S3 settings
+	StorageBackend  string
+	StorageLocalDir string
+	S3Endpoint      string
+	S3Region        string
+	S3Bucket        string
+	S3AccessKey     string
+	S3SecretKey     string
+	S3PathStyle     bool
+	S3Timeout       time.Duration // Limit on each S3 request, reading a download included
+
+	// Attachment limits
+	AttachmentMaxSize      int64         // Largest upload in bytes
+	AttachmentAllowedTypes []string      // MIME types accepted for upload
+	ThumbnailSize          int           // Longest side of generated image thumbnails, in pixels
+	AttachmentUnsentTTL    time.Duration // How long uploads never sent with a message are kept, 0 is forever
+
+	RoomMaxPins int // Most messages pinned in one room at a time
+
+	ScheduledDispatchInterval time.Duration // How often due scheduled messages are looked for
+
+	// Retention: rooms without their own policy keep messages for MessageRetentionDays (0 is forever)
+	MessageRetentionDays   int
+	MessageRetentionAction string // "delete" or "archive"
+	SystemLogRetentionDays int    // 0 keeps system logs forever
+	PurgeInterval          time.Duration
+	PurgeBatchSize         int           // Most threads or log rows removed per transaction
+	ExportResultTTL        time.Duration // How long background export results are kept, 0 is forever
+
+	// Room invites expire after InviteDefaultTTL unless given an expiry, which may be at most InviteMaxTTL away
+	InviteDefaultTTL time.Duration
+	InviteMaxTTL     time.Duration
+
+	ConversationMaxParticipants int // Most users in a group DM, its creator included
 }
 
 var AppConfig *Config
 
+// defaultAttachmentTypes are the MIME types accepted for upload unless ATTACHMENT_ALLOWED_TYPES is set.
+var defaultAttachmentTypes = []string{
+	"image/jpeg", "image/png", "image/gif", "image/webp",
+	"application/pdf", "application/zip", "text/plain",
+}
+
 func LoadConfig() {
 	err := godotenv.Load()
 	if err != nil {
@@ -30,6 +84,56 @@ func LoadConfig() {
 		DBPassword: getEnv("DB_PASSWORD", ""),
 		DBName:     getEnv("DB_NAME", "mydb"),
 		DBSSLMode:  getEnv("DB_SSLMODE", "disable"),
+
+		WSPingInterval:   getEnvDuration("WS_PING_INTERVAL", 30*time.Second),
+		WSPongWait:       getEnvDuration("WS_PONG_WAIT", 60*time.Second),
+		WSWriteWait:      getEnvDuration("WS_WRITE_WAIT", 10*time.Second),
+		WSMaxMessageSize: int64(getEnvInt("WS_MAX_MESSAGE_SIZE", 8192)),
+		WSResumeMaxGap:   getEnvInt("WS_RESUME_MAX_GAP", 200),
+
+		Broadcaster: getEnv("BROADCASTER", "memory"),
+
+		StorageBackend:  getEnv("STORAGE_BACKEND", "local"),
+		StorageLocalDir: getEnv("STORAGE_LOCAL_DIR", "./uploads"),
+		S3Endpoint:      getEnv("S3_ENDPOINT", ""),
+		S3Region:        getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:        getEnv("S3_BUCKET", ""),
+		S3AccessKey:     getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:     getEnv("S3_SECRET_KEY", ""),
+		S3PathStyle:     getEnv("S3_PATH_STYLE", "true") == "true",
+		S3Timeout:       getEnvDuration("S3_TIMEOUT", time.Minute),
+
+		AttachmentMaxSize:      int64(getEnvInt("ATTACHMENT_MAX_SIZE", 10<<20)),
+		AttachmentAllowedTypes: getEnvList("ATTACHMENT_ALLOWED_TYPES", defaultAttachmentTypes),
+		ThumbnailSize:          getEnvInt("THUMBNAIL_SIZE", 320),
+		AttachmentUnsentTTL:    getEnvDuration("ATTACHMENT_UNSENT_TTL", 24*time.Hour),
+
+		RoomMaxPins: getEnvInt("ROOM_MAX_PINS", 50),
+
+		ScheduledDispatchInterval: getEnvDuration("SCHEDULED_DISPATCH_INTERVAL", 5*time.Second),
+
+		MessageRetentionDays:   getEnvInt("MESSAGE_RETENTION_DAYS", 0),
+		MessageRetentionAction: getEnv("MESSAGE_RETENTION_ACTION", "delete"),
+		SystemLogRetentionDays: getEnvInt("SYSTEM_LOG_RETENTION_DAYS", 0),
+		PurgeInterval:          getEnvDuration("PURGE_INTERVAL", time.Hour),
+		PurgeBatchSize:         getEnvInt("PURGE_BATCH_SIZE", 500),
+		ExportResultTTL:        getEnvDuration("EXPORT_RESULT_TTL", 7*24*time.Hour),
+
+		InviteDefaultTTL: getEnvDuration("INVITE_DEFAULT_TTL", 7*24*time.Hour),
+		InviteMaxTTL:     getEnvDuration("INVITE_MAX_TTL", 30*24*time.Hour),
+
+		ConversationMaxParticipants: getEnvInt("DM_MAX_PARTICIPANTS", 10),
+	}
+
+	// A ping must be sent before the peer's pong deadline runs out
+	if AppConfig.WSPingInterval >= AppConfig.WSPongWait {
+		log.Println("⚠️  Warning: WS_PING_INTERVAL must be shorter than WS_PONG_WAIT. Using 90% of WS_PONG_WAIT.")
+		AppConfig.WSPingInterval = AppConfig.WSPongWait * 9 / 10
+	}
+
+	if AppConfig.InviteDefaultTTL > AppConfig.InviteMaxTTL {
+		log.Println("⚠️  Warning: INVITE_DEFAULT_TTL is longer than INVITE_MAX_TTL. Using INVITE_MAX_TTL.")
+		AppConfig.InviteDefaultTTL = AppConfig.InviteMaxTTL
 	}
 }
 
@@ -39,3 +143,43 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("⚠️  Warning: Invalid integer for %s, using default %d\n", key, fallback)
+		return fallback
+	}
+	return parsed
+}
+
+func getEnvList(key string, fallback []string) []string {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed <= 0 {
+		log.Printf("⚠️  Warning: Invalid duration for %s, using default %s\n", key, fallback)
+		return fallback
+	}
+	return parsed
+}
@@ -1,24 +1,18 @@
 package db
 
 import (
+	"chatingApp/config"
 	"database/sql"
 	"fmt"
-	"log"
-	"chatingApp/config"
 	_ "github.com/lib/pq"
+	"log"
 )
 
 // ExecuteSQL executes an SQL query that is sent to it
 func ExecuteSQL(query string) {
 	config.LoadConfig()
 
-	connStr := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		config.AppConfig.DBHost, config.AppConfig.DBPort, config.AppConfig.DBUser,
-		config.AppConfig.DBPassword, config.AppConfig.DBName, config.AppConfig.DBSSLMode,
-	)
-
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("postgres", ConnectionString())
 	if err != nil {
 		log.Fatal("❌ ERROR: Failed to connect to the database:", err)
 	}
@@ -58,4 +52,4 @@ func ResetDatabase() {
 
 	ConnectDB() // Re-run migrations
 	fmt.Println("✅ SUCCESS: Database has been reset and reinitialized.")
-}
\ No newline at end of file
+}
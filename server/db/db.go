@@ -1,28 +1,31 @@
 package db
 
 import (
-	"database/sql"
-	"fmt"
-	"log"
 	"chatingApp/config"
 	"chatingApp/db/migrations"
+	"database/sql"
+	"fmt"
 	_ "github.com/lib/pq"
+	"log"
 )
 
 var DB *sql.DB
 
-// ConnectDB initializes the database connection and applies migrations
-func ConnectDB() {
-	config.LoadConfig()
-
-	connStr := fmt.Sprintf(
+// ConnectionString builds the Postgres connection string from the loaded config
+func ConnectionString() string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		config.AppConfig.DBHost, config.AppConfig.DBPort, config.AppConfig.DBUser,
 		config.AppConfig.DBPassword, config.AppConfig.DBName, config.AppConfig.DBSSLMode,
 	)
+}
+
+// ConnectDB initializes the database connection and applies migrations
+func ConnectDB() {
+	config.LoadConfig()
 
 	var err error
-	DB, err = sql.Open("postgres", connStr)
+	DB, err = sql.Open("postgres", ConnectionString())
 	if err != nil {
 		log.Fatal("❌ Error: Failed to connect to the database:", err)
 	}
@@ -25,7 +25,6 @@ func CreateTables(db *sql.DB) {
 	        name TEXT NOT NULL,
 	        description TEXT,
 	        created_by INT REFERENCES users(id) ON DELETE CASCADE,
-	        room_admins INT[] DEFAULT '{}', -- List of user IDs as admins
 	        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 	        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
         );`,
@@ -53,6 +52,270 @@ func CreateTables(db *sql.DB) {
 			message TEXT NOT NULL,
 			timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 		);`,
+
+		// Per-room message sequence numbers; rooms.last_seq hands out the next one
+		`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS last_seq BIGINT NOT NULL DEFAULT 0;`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS seq BIGINT;`,
+		`UPDATE messages m SET seq = numbered.seq
+		 FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY room_id ORDER BY id) AS seq FROM messages) numbered
+		 WHERE m.id = numbered.id AND m.seq IS NULL;`,
+		`UPDATE rooms r SET last_seq = latest.seq
+		 FROM (SELECT room_id, MAX(seq) AS seq FROM messages GROUP BY room_id) latest
+		 WHERE r.id = latest.room_id AND r.last_seq < latest.seq;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS messages_room_seq_idx ON messages (room_id, seq);`,
+
+		`CREATE TABLE IF NOT EXISTS room_read_markers (
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			last_read_message_id INT NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (room_id, user_id)
+		);`,
+
+		// Edited and soft-deleted messages; previous versions are kept in message_edits
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS edited_at TIMESTAMP;`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_by INT REFERENCES users(id) ON DELETE SET NULL;`,
+		`CREATE TABLE IF NOT EXISTS message_edits (
+			id SERIAL PRIMARY KEY,
+			message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+			content TEXT NOT NULL,
+			edited_by INT REFERENCES users(id) ON DELETE SET NULL,
+			edited_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS message_edits_message_idx ON message_edits (message_id);`,
+
+		// Threaded replies; a reply points at the top-level message that started the thread
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS parent_id INT REFERENCES messages(id) ON DELETE CASCADE;`,
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS reply_count INT NOT NULL DEFAULT 0;`,
+		`CREATE INDEX IF NOT EXISTS messages_parent_idx ON messages (parent_id, id) WHERE parent_id IS NOT NULL;`,
+		`CREATE TABLE IF NOT EXISTS thread_followers (
+			message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (message_id, user_id)
+		);`,
+
+		`CREATE TABLE IF NOT EXISTS message_reactions (
+			message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			emoji TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (message_id, user_id, emoji)
+		);`,
+
+		// One row per mentioned user; @room mentions are fanned out to every member
+		`CREATE TABLE IF NOT EXISTS mentions (
+			id SERIAL PRIMARY KEY,
+			message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			mentioned_by INT REFERENCES users(id) ON DELETE SET NULL,
+			kind TEXT CHECK (kind IN ('user', 'room')) NOT NULL,
+			read_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			UNIQUE (message_id, user_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS mentions_user_idx ON mentions (user_id, id);`,
+
+		// Full-text search; the 'simple' configuration does no stemming, so every language is matched alike
+		`ALTER TABLE messages ADD COLUMN IF NOT EXISTS search_vector tsvector
+			GENERATED ALWAYS AS (to_tsvector('simple', content)) STORED;`,
+		`CREATE INDEX IF NOT EXISTS messages_search_idx ON messages USING GIN (search_vector);`,
+
+		// Uploaded files; message_id stays NULL until the upload is sent with a message
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id SERIAL PRIMARY KEY,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			message_id INT REFERENCES messages(id) ON DELETE CASCADE,
+			uploader_id INT REFERENCES users(id) ON DELETE SET NULL,
+			filename TEXT NOT NULL,
+			content_type TEXT NOT NULL,
+			size BIGINT NOT NULL,
+			storage_key TEXT NOT NULL,
+			thumbnail_key TEXT,
+			width INT,
+			height INT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS attachments_message_idx ON attachments (message_id);`,
+
+		// Messages pinned by room moderators
+		`CREATE TABLE IF NOT EXISTS pinned_messages (
+			message_id INT PRIMARY KEY REFERENCES messages(id) ON DELETE CASCADE,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			pinned_by INT REFERENCES users(id) ON DELETE SET NULL,
+			pinned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS pinned_messages_room_idx ON pinned_messages (room_id, pinned_at);`,
+
+		// Messages waiting to be posted at a later time
+		`CREATE TABLE IF NOT EXISTS scheduled_messages (
+			id SERIAL PRIMARY KEY,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			parent_id INT REFERENCES messages(id) ON DELETE CASCADE,
+			content TEXT NOT NULL,
+			send_at TIMESTAMPTZ NOT NULL, -- Unlike the other times, sent by clients with their own offset
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'cancelled', 'failed')),
+			message_id INT REFERENCES messages(id) ON DELETE SET NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS scheduled_messages_due_idx ON scheduled_messages (send_at) WHERE status = 'pending';`,
+		`CREATE INDEX IF NOT EXISTS scheduled_messages_user_idx ON scheduled_messages (user_id, send_at);`,
+
+		// Message retention; NULL columns fall back to the server-wide policy, 0 days keeps messages forever
+		`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS retention_days INT CHECK (retention_days >= 0);`,
+		`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS retention_action TEXT CHECK (retention_action IN ('delete', 'archive'));`,
+		`CREATE INDEX IF NOT EXISTS messages_room_created_idx ON messages (room_id, created_at);`,
+		`CREATE INDEX IF NOT EXISTS system_logs_timestamp_idx ON system_logs (timestamp);`,
+		`CREATE TABLE IF NOT EXISTS archived_messages (
+			id INT PRIMARY KEY, -- ID the message had in messages
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			user_id INT,
+			seq BIGINT,
+			parent_id INT,
+			content TEXT NOT NULL,
+			edited_at TIMESTAMP,
+			deleted_at TIMESTAMP,
+			deleted_by INT,
+			created_at TIMESTAMP,
+			archived_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS purge_runs (
+			id SERIAL PRIMARY KEY,
+			started_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP NOT NULL,
+			messages_deleted INT NOT NULL DEFAULT 0,
+			messages_archived INT NOT NULL DEFAULT 0,
+			system_logs_deleted INT NOT NULL DEFAULT 0,
+			error TEXT
+		);`,
+
+		// Room transcripts exported in the background; the file is kept in attachment storage
+		`CREATE TABLE IF NOT EXISTS export_jobs (
+			id SERIAL PRIMARY KEY,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			requested_by INT REFERENCES users(id) ON DELETE CASCADE,
+			format TEXT NOT NULL CHECK (format IN ('json', 'csv', 'html', 'txt')),
+			from_time TIMESTAMPTZ,
+			to_time TIMESTAMPTZ,
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+			storage_key TEXT,
+			size BIGINT,
+			error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			started_at TIMESTAMPTZ,
+			finished_at TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS export_jobs_status_idx ON export_jobs (status, id) WHERE status IN ('pending', 'running');`,
+		// Finished export jobs are removed by the purger after EXPORT_RESULT_TTL
+		`CREATE INDEX IF NOT EXISTS export_jobs_finished_idx ON export_jobs (finished_at) WHERE finished_at IS NOT NULL;`,
+		`ALTER TABLE purge_runs ADD COLUMN IF NOT EXISTS exports_expired INT NOT NULL DEFAULT 0;`,
+
+		// Room roles live on the membership row; every room has exactly one owner
+		`ALTER TABLE room_users ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('owner', 'moderator', 'member'));`,
+		`ALTER TABLE room_users ADD COLUMN IF NOT EXISTS joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;`,
+		// Convert the old rooms.room_admins arrays: admins that still exist become moderators (joining
+		// the room if needed) and each room gets an owner, preferring its creator, then another admin,
+		// then its longest-standing member
+		`DO $$
+		BEGIN
+			IF EXISTS (SELECT 1 FROM information_schema.columns
+					   WHERE table_schema = current_schema() AND table_name = 'rooms' AND column_name = 'room_admins') THEN
+				INSERT INTO room_users (room_id, user_id)
+				SELECT r.id, a.user_id FROM rooms r CROSS JOIN LATERAL UNNEST(r.room_admins) AS a(user_id)
+				JOIN users u ON u.id = a.user_id
+				ON CONFLICT DO NOTHING;
+
+				UPDATE room_users ru SET role = 'moderator'
+				FROM rooms r WHERE r.id = ru.room_id AND ru.user_id = ANY(r.room_admins);
+
+				UPDATE room_users ru SET role = 'owner'
+				FROM (
+					SELECT DISTINCT ON (m.room_id) m.room_id, m.user_id
+					FROM room_users m JOIN rooms r ON r.id = m.room_id
+					ORDER BY m.room_id, m.user_id = r.created_by DESC, m.role = 'moderator' DESC, m.joined_at, m.user_id
+				) o
+				WHERE ru.room_id = o.room_id AND ru.user_id = o.user_id;
+
+				ALTER TABLE rooms DROP COLUMN room_admins;
+			END IF;
+		END $$;`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS room_users_owner_idx ON room_users (room_id) WHERE role = 'owner';`,
+
+		// Invite codes to join a room, and who joined through each of them
+		`CREATE TABLE IF NOT EXISTS room_invites (
+			id SERIAL PRIMARY KEY,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			code TEXT NOT NULL UNIQUE,
+			created_by INT REFERENCES users(id) ON DELETE SET NULL,
+			target_user_id INT REFERENCES users(id) ON DELETE CASCADE, -- Only this user may accept, if set
+			max_uses INT CHECK (max_uses > 0), -- NULL is unlimited
+			uses INT NOT NULL DEFAULT 0,
+			expires_at TIMESTAMPTZ NOT NULL,
+			revoked_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS room_invites_room_idx ON room_invites (room_id, id);`,
+		`CREATE TABLE IF NOT EXISTS room_invite_uses (
+			invite_id INT REFERENCES room_invites(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (invite_id, user_id)
+		);`,
+
+		// Who can find and join a room: public rooms are open to all, request rooms need a
+		// moderator's approval and private rooms are invite only
+		`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS visibility TEXT NOT NULL DEFAULT 'private'
+			CHECK (visibility IN ('public', 'private', 'request'));`,
+		`CREATE INDEX IF NOT EXISTS rooms_visibility_idx ON rooms (visibility, id);`,
+		`CREATE TABLE IF NOT EXISTS room_join_requests (
+			id SERIAL PRIMARY KEY,
+			room_id INT REFERENCES rooms(id) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			message TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'denied', 'cancelled')),
+			decided_by INT REFERENCES users(id) ON DELETE SET NULL,
+			decided_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);`,
+		// A user has at most one pending request per room
+		`CREATE UNIQUE INDEX IF NOT EXISTS room_join_requests_pending_idx
+			ON room_join_requests (room_id, user_id) WHERE status = 'pending';`,
+
+		// Direct and group conversations are rooms without an owner. dm_key, the sorted IDs of
+		// their participants, makes sure the same users always share one conversation
+		`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'room'
+			CHECK (kind IN ('room', 'direct', 'group'));`,
+		`ALTER TABLE rooms ADD COLUMN IF NOT EXISTS dm_key TEXT UNIQUE;`,
+
+		// Presence shared between server instances: each live instance reports the status of its
+		// own connections of every user, and heartbeats so a crashed one stops counting
+		`CREATE TABLE IF NOT EXISTS presence_nodes (
+			node TEXT PRIMARY KEY,
+			seen_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE TABLE IF NOT EXISTS presence_connections (
+			node TEXT REFERENCES presence_nodes(node) ON DELETE CASCADE,
+			user_id INT REFERENCES users(id) ON DELETE CASCADE,
+			status TEXT NOT NULL CHECK (status IN ('online', 'away')),
+			PRIMARY KEY (node, user_id)
+		);`,
+		`CREATE INDEX IF NOT EXISTS presence_connections_user_idx ON presence_connections (user_id);`,
+
+		// Hub events too large for a NOTIFY payload, loaded by the other instances by ID
+		`CREATE TABLE IF NOT EXISTS hub_events (
+			id BIGSERIAL PRIMARY KEY,
+			event JSONB NOT NULL,
+			created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+		);`,
+		`CREATE INDEX IF NOT EXISTS hub_events_created_at_idx ON hub_events (created_at);`,
+
+		// Uploads never sent with a message are removed by the purger after ATTACHMENT_UNSENT_TTL
+		`CREATE INDEX IF NOT EXISTS attachments_unsent_idx ON attachments (created_at) WHERE message_id IS NULL;`,
+		`ALTER TABLE purge_runs ADD COLUMN IF NOT EXISTS uploads_expired INT NOT NULL DEFAULT 0;`,
 	}
 
 	for _, query := range queries {
@@ -0,0 +1,116 @@
+package migrations_test
+
+import (
+	"chatingApp/db/migrations"
+	"chatingApp/dbtest"
+	"fmt"
+	"testing"
+
+	"github.com/lib/pq"
+)
+
+// TestRoomAdminsConversion migrates rooms from the rooms.room_admins arrays to room roles.
+func TestRoomAdminsConversion(t *testing.T) {
+	db, _ := dbtest.Open(t)
+
+	// Put the schema back as it was before room roles: admins in an array, every member plain
+	dbtest.Exec(t, db, `ALTER TABLE rooms ADD COLUMN room_admins INT[] NOT NULL DEFAULT '{}';`)
+
+	users := map[string]int{}
+	for _, name := range []string{"creator", "admin1", "admin2", "older", "newer", "other"} {
+		var id int
+		err := db.QueryRow(`INSERT INTO users (name, email, password, role) VALUES ($1, $1 || '@example.com', 'x', 'user') RETURNING id;`, name).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		users[name] = id
+	}
+	const removedUser = 999999 // An admin whose account no longer exists
+
+	room := func(creator string, admins ...int) int {
+		t.Helper()
+		var id int
+		err := db.QueryRow(`INSERT INTO rooms (name, created_by, room_admins) VALUES ('room', $1, $2) RETURNING id;`,
+			users[creator], pq.Array(admins)).Scan(&id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id
+	}
+	join := func(roomID int, name string, joinedAt string) {
+		t.Helper()
+		dbtest.Exec(t, db, fmt.Sprintf(`INSERT INTO room_users (room_id, user_id, joined_at) VALUES (%d, %d, '%s');`,
+			roomID, users[name], joinedAt))
+	}
+
+	// The creator is a plain member and another user is an admin: the creator still wins
+	creatorRoom := room("creator", users["admin1"])
+	join(creatorRoom, "admin1", "2020-01-01")
+	join(creatorRoom, "creator", "2020-06-01")
+
+	// The creator left: an admin who was not even a member yet beats earlier members,
+	// and admins whose accounts are gone are dropped
+	adminRoom := room("creator", users["admin2"], removedUser)
+	join(adminRoom, "older", "2020-01-01")
+
+	// No creator and no admins: the longest-standing member wins, even with a higher user ID
+	memberRoom := room("creator")
+	join(memberRoom, "older", "2021-01-01")
+	join(memberRoom, "newer", "2020-01-01")
+
+	// Several admins: the longest-standing of them wins
+	tiedRoom := room("other", users["admin1"], users["admin2"])
+	join(tiedRoom, "admin2", "2020-01-01")
+	join(tiedRoom, "admin1", "2020-06-01")
+
+	migrations.CreateTables(db)
+
+	roles := func(roomID int) map[int]string {
+		t.Helper()
+		rows, err := db.Query(`SELECT user_id, role FROM room_users WHERE room_id = $1;`, roomID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		got := map[int]string{}
+		for rows.Next() {
+			var userID int
+			var role string
+			if err := rows.Scan(&userID, &role); err != nil {
+				t.Fatal(err)
+			}
+			got[userID] = role
+		}
+		return got
+	}
+	expect := func(name string, roomID int, want map[int]string) {
+		t.Helper()
+		got := roles(roomID)
+		if len(got) != len(want) {
+			t.Errorf("%s: roles %v, want %v", name, got, want)
+			return
+		}
+		for userID, role := range want {
+			if got[userID] != role {
+				t.Errorf("%s: roles %v, want %v", name, got, want)
+				return
+			}
+		}
+	}
+
+	expect("creator room", creatorRoom, map[int]string{users["creator"]: "owner", users["admin1"]: "moderator"})
+	expect("admin room", adminRoom, map[int]string{users["admin2"]: "owner", users["older"]: "member"})
+	expect("member room", memberRoom, map[int]string{users["newer"]: "owner", users["older"]: "member"})
+	expect("tied room", tiedRoom, map[int]string{users["admin2"]: "owner", users["admin1"]: "moderator"})
+
+	var exists bool
+	err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM information_schema.columns
+						WHERE table_schema = current_schema() AND table_name = 'rooms' AND column_name = 'room_admins');`).Scan(&exists)
+	if err != nil || exists {
+		t.Errorf("rooms.room_admins still exists (%v)", err)
+	}
+
+	// Running the migrations again leaves the roles alone
+	migrations.CreateTables(db)
+	expect("creator room after a second run", creatorRoom, map[int]string{users["creator"]: "owner", users["admin1"]: "moderator"})
+}
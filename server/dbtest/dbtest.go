@@ -0,0 +1,83 @@
+// Package dbtest gives tests a migrated Postgres database of their own.
+package dbtest
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"chatingApp/db/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+// Open connects to the database in TEST_DATABASE_URL and migrates a fresh schema that is
+// dropped when the test ends. It returns the connection and its connection string. Tests
+// are skipped when TEST_DATABASE_URL is not set.
+func Open(t *testing.T) (*sql.DB, string) {
+	t.Helper()
+	base := os.Getenv("TEST_DATABASE_URL")
+	if base == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		t.Fatal(err)
+	}
+	schema := "test_" + hex.EncodeToString(suffix)
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema + `;`); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE;`); err != nil {
+			t.Error(err)
+		}
+	})
+
+	connStr := withSearchPath(t, base, schema)
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations.CreateTables(db)
+	return db, connStr
+}
+
+// Exec runs statements that set up a test, failing it on the first error.
+func Exec(t *testing.T, db *sql.DB, queries ...string) {
+	t.Helper()
+	for _, query := range queries {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatalf("%v\n%s", err, query)
+		}
+	}
+}
+
+// withSearchPath points a URL or key=value connection string at the schema.
+func withSearchPath(t *testing.T, connStr, schema string) string {
+	if !strings.Contains(connStr, "://") {
+		return connStr + " search_path=" + schema
+	}
+
+	u, err := url.Parse(connStr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"chatingApp/config"
+	"chatingApp/middleware"
+	"chatingApp/services"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead allows for the multipart framing around an uploaded file.
+const multipartOverhead = 1 << 20
+
+// AttachmentHandler handles HTTP requests for file attachments.
+type AttachmentHandler struct {
+	AttachmentService *services.AttachmentService
+}
+
+// NewAttachmentHandler creates a new AttachmentHandler instance.
+func NewAttachmentHandler(service *services.AttachmentService) *AttachmentHandler {
+	return &AttachmentHandler{AttachmentService: service}
+}
+
+// UploadAttachment handles the POST request to upload a file to a room.
+// The file goes in the multipart field "file"; send its ID with message.send to share it.
+func (h *AttachmentHandler) UploadAttachment(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	// Stop reading oversized bodies instead of spooling them to disk
+	maxBody := config.AppConfig.AttachmentMaxSize + multipartOverhead
+	if c.Request.ContentLength > maxBody {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBody)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": services.ErrAttachmentTooLarge.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file"})
+		return
+	}
+
+	attachment, err := h.AttachmentService.Upload(c.Request.Context(), roomID, userID, header)
+	switch {
+	case errors.Is(err, services.ErrUserNotInRoom):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrEmptyAttachment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAttachmentTypeNotAllowed):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload attachment"})
+	default:
+		c.JSON(http.StatusCreated, attachment)
+	}
+}
+
+// DownloadAttachment handles the GET request to download an attachment.
+func (h *AttachmentHandler) DownloadAttachment(c *gin.Context) {
+	h.serveAttachment(c, false)
+}
+
+// DownloadThumbnail handles the GET request to download the thumbnail of an image attachment.
+func (h *AttachmentHandler) DownloadThumbnail(c *gin.Context) {
+	h.serveAttachment(c, true)
+}
+
+// serveAttachment streams an attachment, or its thumbnail, from storage to a room member.
+func (h *AttachmentHandler) serveAttachment(c *gin.Context, thumbnail bool) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	attachmentID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID"})
+		return
+	}
+
+	attachment, reader, err := h.AttachmentService.Open(c.Request.Context(), attachmentID, userID, thumbnail)
+	if errors.Is(err, services.ErrAttachmentNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachment"})
+		return
+	}
+	defer reader.Close()
+
+	contentType, size := attachment.ContentType, attachment.Size
+	if thumbnail {
+		// Thumbnails are PNG except for JPEG photos
+		contentType, size = "image/png", -1
+		if attachment.ContentType == "image/jpeg" {
+			contentType = "image/jpeg"
+		}
+	}
+
+	// Only images are shown in the browser; everything else is downloaded
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") {
+		disposition = "inline"
+	}
+
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType(disposition, map[string]string{"filename": attachment.Filename}),
+		"X-Content-Type-Options": "nosniff",
+		"Cache-Control":          "private, max-age=3600",
+	})
+}
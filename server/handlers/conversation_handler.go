@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConversationHandler handles HTTP requests for direct and group conversations.
+type ConversationHandler struct {
+	ConversationService *services.ConversationService
+}
+
+// NewConversationHandler creates a new ConversationHandler instance.
+func NewConversationHandler(service *services.ConversationService) *ConversationHandler {
+	return &ConversationHandler{ConversationService: service}
+}
+
+// OpenConversation handles the POST request to open a conversation with other users.
+// Responds 201 when the conversation is new and 200 with the existing one otherwise.
+func (h *ConversationHandler) OpenConversation(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input models.ConversationCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	conversation, created, err := h.ConversationService.OpenConversation(userID, input.UserIDs)
+	if err != nil {
+		respondConversationError(c, err, "Failed to open conversation")
+		return
+	}
+
+	if created {
+		c.JSON(http.StatusCreated, conversation)
+		return
+	}
+	c.JSON(http.StatusOK, conversation)
+}
+
+// GetConversations handles the GET request to list the caller's conversations with unread counts.
+func (h *ConversationHandler) GetConversations(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversations, err := h.ConversationService.GetConversations(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve conversations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"conversations": conversations})
+}
+
+// respondConversationError maps conversation service errors to HTTP responses.
+func respondConversationError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrConversationTooSmall),
+		errors.Is(err, services.ErrConversationTooLarge),
+		errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotInRoom):
+		c.JSON(http.StatusForbidden, gin.H{"error": "User not in conversation"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExportHandler handles HTTP requests for room transcript exports.
+type ExportHandler struct {
+	ExportService *services.ExportService
+}
+
+// NewExportHandler creates a new ExportHandler instance.
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{ExportService: service}
+}
+
+// ExportRoom handles the GET request to stream the transcript of a room.
+// Supports format=json|csv|html|txt (default json) and from and to bounds.
+func (h *ExportHandler) ExportRoom(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, ok := parseExportQuery(c)
+	if !ok {
+		return
+	}
+
+	if err := h.ExportService.AuthorizeExport(query, userID, role); err != nil {
+		respondExportError(c, err, "Failed to export room")
+		return
+	}
+
+	c.Header("Content-Type", services.ExportContentType(query.Format))
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": services.ExportFilename(query.RoomID, query.Format),
+	}))
+	c.Status(http.StatusOK)
+
+	// Headers are out once the first bytes are, so a failure can only cut the transcript short
+	if err := h.ExportService.WriteExport(c.Request.Context(), query, c.Writer); err != nil {
+		log.Println("❌ Error: Room export interrupted:", err)
+	}
+}
+
+// CreateExportJob handles the POST request to build the transcript of a room in the background.
+// Takes the same query parameters as ExportRoom.
+func (h *ExportHandler) CreateExportJob(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, ok := parseExportQuery(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.ExportService.CreateExportJob(query, userID, role)
+	if err != nil {
+		respondExportError(c, err, "Failed to create export job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetExportJob handles the GET request to check on a background export.
+func (h *ExportHandler) GetExportJob(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export job ID"})
+		return
+	}
+
+	job, err := h.ExportService.GetExportJob(jobID, userID, role)
+	if err != nil {
+		respondExportError(c, err, "Failed to retrieve export job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadExport handles the GET request to download the result of a finished background export.
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export job ID"})
+		return
+	}
+
+	job, reader, err := h.ExportService.OpenExportResult(c.Request.Context(), jobID, userID, role)
+	if err != nil {
+		respondExportError(c, err, "Failed to download export")
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, job.Size, services.ExportContentType(job.Format), reader, map[string]string{
+		"Content-Disposition":    mime.FormatMediaType("attachment", map[string]string{"filename": services.ExportFilename(job.RoomID, job.Format)}),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// parseExportQuery reads the room, format and date bounds of an export request.
+func parseExportQuery(c *gin.Context) (models.ExportQuery, bool) {
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return models.ExportQuery{}, false
+	}
+
+	from, errFrom := parseTimeBound(c.Query("from"), false)
+	to, errTo := parseTimeBound(c.Query("to"), true)
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range, use RFC 3339 or YYYY-MM-DD"})
+		return models.ExportQuery{}, false
+	}
+
+	return models.ExportQuery{
+		RoomID: roomID,
+		Format: c.DefaultQuery("format", models.ExportJSON),
+		From:   from,
+		To:     to,
+	}, true
+}
+
+// respondExportError maps export service errors to HTTP responses.
+func respondExportError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidExportFormat):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRoomAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room moderators, the owner and super-admins can export a room"})
+	case errors.Is(err, services.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+	case errors.Is(err, services.ErrExportJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrExportNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InviteHandler handles HTTP requests for room invites.
+type InviteHandler struct {
+	InviteService *services.InviteService
+}
+
+// NewInviteHandler creates a new InviteHandler instance.
+func NewInviteHandler(service *services.InviteService) *InviteHandler {
+	return &InviteHandler{InviteService: service}
+}
+
+// CreateInvite handles the POST request to create an invite to a room.
+// The body may set expires_at, max_uses and user_id to limit who can join and for how long.
+func (h *InviteHandler) CreateInvite(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	var input models.InviteCreateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	invite, err := h.InviteService.CreateInvite(roomID, userID, input)
+	if err != nil {
+		respondInviteError(c, err, "Failed to create invite")
+		return
+	}
+
+	c.JSON(http.StatusCreated, invite)
+}
+
+// GetRoomInvites handles the GET request to list the invites of a room.
+// With active=true only invites that can still be accepted are listed.
+func (h *InviteHandler) GetRoomInvites(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	activeOnly, err := strconv.ParseBool(c.DefaultQuery("active", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid active flag"})
+		return
+	}
+
+	invites, err := h.InviteService.GetRoomInvites(roomID, userID, activeOnly)
+	if err != nil {
+		respondInviteError(c, err, "Failed to retrieve invites")
+		return
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+// RevokeInvite handles the DELETE request to revoke an invite of a room.
+func (h *InviteHandler) RevokeInvite(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, inviteID, ok := parseInviteParams(c)
+	if !ok {
+		return
+	}
+
+	invite, err := h.InviteService.RevokeInvite(roomID, inviteID, userID)
+	if err != nil {
+		respondInviteError(c, err, "Failed to revoke invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, invite)
+}
+
+// GetInviteUses handles the GET request to list who joined a room through an invite.
+func (h *InviteHandler) GetInviteUses(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, inviteID, ok := parseInviteParams(c)
+	if !ok {
+		return
+	}
+
+	uses, err := h.InviteService.GetInviteUses(roomID, inviteID, userID)
+	if err != nil {
+		respondInviteError(c, err, "Failed to retrieve invite uses")
+		return
+	}
+
+	c.JSON(http.StatusOK, uses)
+}
+
+// PreviewInvite handles the GET request to see which room an invite code is for.
+func (h *InviteHandler) PreviewInvite(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	preview, err := h.InviteService.PreviewInvite(c.Param("code"), userID)
+	if err != nil {
+		respondInviteError(c, err, "Failed to retrieve invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
+
+// AcceptInvite handles the POST request to join a room with an invite code.
+// Accepting an invite to a room the user is already in succeeds with joined set to false.
+func (h *InviteHandler) AcceptInvite(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	room, joined, err := h.InviteService.AcceptInvite(c.Param("code"), userID)
+	if err != nil {
+		respondInviteError(c, err, "Failed to accept invite")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"room": room, "joined": joined})
+}
+
+// parseInviteParams reads the room and invite IDs from the request path.
+func parseInviteParams(c *gin.Context) (int, int, bool) {
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return 0, 0, false
+	}
+
+	inviteID, err := strconv.Atoi(c.Param("inviteID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return 0, 0, false
+	}
+
+	return roomID, inviteID, true
+}
+
+// respondInviteError maps invite service errors to HTTP responses.
+func respondInviteError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidInviteExpiry), errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRoomAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room moderators and the owner can manage invites"})
+	case errors.Is(err, services.ErrInviteNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInviteUnavailable):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
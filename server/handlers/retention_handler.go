@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RetentionHandler handles HTTP requests for message retention.
+type RetentionHandler struct {
+	RetentionService *services.RetentionService
+}
+
+// NewRetentionHandler creates a new RetentionHandler instance.
+func NewRetentionHandler(service *services.RetentionService) *RetentionHandler {
+	return &RetentionHandler{RetentionService: service}
+}
+
+// GetRoomRetention handles the GET request to view the retention policy of a room.
+func (h *RetentionHandler) GetRoomRetention(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	policy, err := h.RetentionService.GetRoomRetention(roomID, userID)
+	if err != nil {
+		respondRetentionError(c, err, "Failed to retrieve retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// SetRoomRetention handles the PUT request to set the retention policy of a room.
+func (h *RetentionHandler) SetRoomRetention(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	var input models.RetentionUpdateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	policy, err := h.RetentionService.SetRoomRetention(roomID, userID, input.RetentionDays, input.Action)
+	if err != nil {
+		respondRetentionError(c, err, "Failed to set retention policy")
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+// GetPurgeRuns handles the GET request to list the latest purge runs.
+func (h *RetentionHandler) GetPurgeRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if err != nil || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	runs, err := h.RetentionService.GetPurgeRuns(limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve purge runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// respondRetentionError maps retention service errors to HTTP responses.
+func respondRetentionError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRetention):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRoomAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room moderators and the owner can do this"})
+	case errors.Is(err, services.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
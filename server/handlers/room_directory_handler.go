@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RoomDirectoryHandler handles HTTP requests for the room directory and join requests.
+type RoomDirectoryHandler struct {
+	DirectoryService *services.RoomDirectoryService
+}
+
+// NewRoomDirectoryHandler creates a new RoomDirectoryHandler instance.
+func NewRoomDirectoryHandler(service *services.RoomDirectoryService) *RoomDirectoryHandler {
+	return &RoomDirectoryHandler{DirectoryService: service}
+}
+
+// GetDirectory handles the GET request to browse the rooms visible to the caller.
+// Supports a q search over names and descriptions and limit/offset paging.
+func (h *RoomDirectoryHandler) GetDirectory(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	offset, errOffset := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if errLimit != nil || errOffset != nil || limit < 0 || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid paging parameters"})
+		return
+	}
+
+	query := models.RoomDirectoryQuery{
+		Query:  c.Query("q"),
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	directory, err := h.DirectoryService.GetDirectory(query, userID, role)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room directory"})
+		return
+	}
+
+	c.JSON(http.StatusOK, directory)
+}
+
+// JoinRoom handles the POST request to join a public room or ask to join a request room.
+// Responds 200 once the user is in the room and 202 while their join request is pending.
+func (h *RoomDirectoryHandler) JoinRoom(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	var input models.JoinRoomRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+	}
+
+	result, err := h.DirectoryService.JoinRoom(roomID, userID, input.Message)
+	if err != nil {
+		respondDirectoryError(c, err, "Failed to join room")
+		return
+	}
+
+	if !result.Joined {
+		c.JSON(http.StatusAccepted, result)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// CancelJoinRequest handles the DELETE request to withdraw the caller's pending join request.
+func (h *RoomDirectoryHandler) CancelJoinRequest(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	request, err := h.DirectoryService.CancelJoinRequest(roomID, userID)
+	if err != nil {
+		respondDirectoryError(c, err, "Failed to cancel join request")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// GetJoinRequests handles the GET request to list the join requests of a room.
+// Lists pending requests unless another status, or all, is asked for.
+func (h *RoomDirectoryHandler) GetJoinRequests(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	status := c.DefaultQuery("status", models.JoinRequestPending)
+	if status == "all" {
+		status = ""
+	}
+
+	requests, err := h.DirectoryService.GetJoinRequests(roomID, userID, status)
+	if err != nil {
+		respondDirectoryError(c, err, "Failed to retrieve join requests")
+		return
+	}
+
+	c.JSON(http.StatusOK, requests)
+}
+
+// ApproveJoinRequest handles the POST request to let a user into a room.
+func (h *RoomDirectoryHandler) ApproveJoinRequest(c *gin.Context) {
+	h.decideJoinRequest(c, true)
+}
+
+// DenyJoinRequest handles the POST request to turn down a join request.
+func (h *RoomDirectoryHandler) DenyJoinRequest(c *gin.Context) {
+	h.decideJoinRequest(c, false)
+}
+
+// decideJoinRequest approves or denies the join request named in the request path.
+func (h *RoomDirectoryHandler) decideJoinRequest(c *gin.Context, approve bool) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	requestID, err := strconv.Atoi(c.Param("requestID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid join request ID"})
+		return
+	}
+
+	request, err := h.DirectoryService.DecideJoinRequest(roomID, requestID, userID, approve)
+	if err != nil {
+		respondDirectoryError(c, err, "Failed to decide join request")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// respondDirectoryError maps room directory service errors to HTTP responses.
+func respondDirectoryError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidJoinRequestStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoomInviteOnly):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrNotRoomAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room moderators and the owner can manage join requests"})
+	case errors.Is(err, services.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+	case errors.Is(err, services.ErrJoinRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrJoinRequestDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"chatingApp/middleware"
 	"chatingApp/models"
 	"chatingApp/services"
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -38,36 +39,19 @@ func (h *RoomHandler) CreateRoom(c *gin.Context) {
 	room := models.Room{
 		Name:        roomInput.Name,
 		Description: roomInput.Description,
+		Visibility:  roomInput.Visibility,
 		CreatedBy:   userID,
-		RoomAdmins:  []int{userID},
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
+	// The creator joins as the room's owner
 	createdRoom, err := h.RoomService.CreateRoom(&room)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create room"})
 		return
 	}
 
-	err = h.RoomService.AddUserToRoom(createdRoom.ID, userID, userID)
-	if err != nil {
-		delErr := h.RoomService.DeleteRoom(createdRoom.ID, userID)
-		if delErr != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":       "Failed to update room users status",
-				"stack":       err.Error(),
-				"deleteError": delErr.Error(),
-			})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Failed to update room users status",
-				"stack": err.Error(),
-			})
-		}
-		return
-	}
-
 	c.JSON(http.StatusCreated, gin.H{"message": "Room created successfully", "room": createdRoom})
 }
 
@@ -81,17 +65,23 @@ func (h *RoomHandler) GetRooms(c *gin.Context) {
 	c.JSON(http.StatusOK, rooms)
 }
 
-// GetRoom handles the GET request to retrieve a room by ID.
+// GetRoom handles the GET request to retrieve a room by ID. Rooms the caller may not see are not found.
 func (h *RoomHandler) GetRoom(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
 	roomID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
 		return
 	}
 
-	room, err := h.RoomService.GetRoom(roomID)
+	room, err := h.RoomService.GetVisibleRoom(roomID, userID, role)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room"})
+		respondRoomAdminError(c, err, "Failed to fetch room")
 		return
 	}
 	c.JSON(http.StatusOK, room)
@@ -113,13 +103,15 @@ func (h *RoomHandler) DeleteRoom(c *gin.Context) {
 
 	err = h.RoomService.DeleteRoom(roomID, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete room"})
+		respondRoomAdminError(c, err, "Failed to delete room")
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "Room deleted successfully"})
 }
 
+// IsUserRoomAdmin handles the GET request to check whether the caller moderates a room.
+// The response also carries the caller's room role, empty if they are not a member.
 func (h *RoomHandler) IsUserRoomAdmin(c *gin.Context) {
 	userID, _, _, err := middleware.ExtractTokenData(c, "user")
 	if err != nil {
@@ -131,119 +123,336 @@ func (h *RoomHandler) IsUserRoomAdmin(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
 		return
 	}
-	exist,err := h.RoomService.IsUserRoomAdmin(roomID, userID)
+	role, err := h.RoomService.Permissions.Role(roomID, userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check admin status"})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"exist": exist})
+	c.JSON(http.StatusOK, gin.H{"exist": services.RoleAllows(role, services.ActionManageRoom), "role": role})
 }
 
+// UpdateRoomDetails handles the PUT request to update a room's name, description, visibility or member list.
+func (h *RoomHandler) UpdateRoomDetails(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-// // UpdateRoomDetails handles the PUT request to update room details.
-// func (h *RoomHandler) UpdateRoomDetails(c *gin.Context) {
-// 	var updateInput models.RoomUpdateRequest
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
 
-// 	roomID, err := strconv.Atoi(c.Param("id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
-// 		return
-// 	}
+	var updateInput models.RoomUpdateRequest
+	if err := c.ShouldBindJSON(&updateInput); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
 
-// 	userID := c.GetInt("user_id")
-// 	if !h.RoomService.IsUserRoomAdmin(roomID, userID) {
-// 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-// 		return
-// 	}
+	room, err := h.RoomService.UpdateRoomDetails(roomID, userID, updateInput)
+	if err != nil {
+		respondRoomAdminError(c, err, "Failed to update room details")
+		return
+	}
 
-// 	if err := c.ShouldBindJSON(&updateInput); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-// 		return
-// 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Room details updated successfully", "room": room})
+}
 
-// 	err = h.RoomService.UpdateRoomDetails(roomID, *updateInput.Name, *updateInput.Description, userID)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room details"})
-// 		return
-// 	}
+// SetMemberRole handles the PUT request to make a member a moderator or a plain member.
+func (h *RoomHandler) SetMemberRole(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-// 	c.JSON(http.StatusOK, gin.H{"message": "Room details updated successfully"})
-// }
+	roomID, memberID, ok := parseMemberParams(c)
+	if !ok {
+		return
+	}
 
-// // UpdateRoomAdmins handles the PUT request to update room admins.
-// func (h *RoomHandler) UpdateRoomAdmins(c *gin.Context) {
-// 	var adminInput struct {
-// 		RoomAdmins []int `json:"room_admins" binding:"required"`
-// 	}
+	var input models.RoomRoleRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
 
-// 	roomID, err := strconv.Atoi(c.Param("id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
-// 		return
-// 	}
+	member, err := h.RoomService.SetMemberRole(roomID, memberID, userID, input.Role)
+	if err != nil {
+		respondRoomAdminError(c, err, "Failed to change room role")
+		return
+	}
 
-// 	userID := c.GetInt("user_id")
-// 	if !h.RoomService.IsUserRoomAdmin(roomID, userID) {
-// 		c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
-// 		return
-// 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Room role changed successfully", "member": member})
+}
 
-// 	if err := c.ShouldBindJSON(&adminInput); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-// 		return
-// 	}
+// TransferOwnership handles the POST request to hand a room to another member.
+func (h *RoomHandler) TransferOwnership(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-// 	err = h.RoomService.UpdateRoomAdmins(roomID, adminInput.RoomAdmins, userID)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update room admins"})
-// 		return
-// 	}
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
 
-// 	c.JSON(http.StatusOK, gin.H{"message": "Room admins updated successfully"})
-// }
+	var input models.RoomTransferRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
 
-// // AddUserToRoom handles adding a user to a room.
-// func (h *RoomHandler) AddUserToRoom(c *gin.Context) {
-// 	var input struct {
-// 		UserID int `json:"user_id" binding:"required"`
-// 	}
+	room, err := h.RoomService.TransferOwnership(roomID, input.UserID, userID)
+	if err != nil {
+		respondRoomAdminError(c, err, "Failed to transfer room ownership")
+		return
+	}
 
-// 	roomID, err := strconv.Atoi(c.Param("id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
-// 		return
-// 	}
+	c.JSON(http.StatusOK, gin.H{"message": "Room ownership transferred successfully", "room": room})
+}
 
-// 	if err := c.ShouldBindJSON(&input); err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
-// 		return
-// 	}
+// AddUserToRoom handles the POST request to add a user to a room.
+func (h *RoomHandler) AddUserToRoom(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-// 	err = h.RoomService.AddUserToRoom(roomID, input.UserID, c.GetInt("user_id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add user to room"})
-// 		return
-// 	}
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
 
-// 	c.JSON(http.StatusOK, gin.H{"message": "User added successfully to room"})
-// }
+	var input struct {
+		UserID int `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
 
-// // GetUsersInRoom handles the GET request to retrieve all users in a room.
-// func (h *RoomHandler) GetUsersInRoom(c *gin.Context) {
-// 	roomID, err := strconv.Atoi(c.Param("id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
-// 		return
-// 	}
+	added, err := h.RoomService.AddUserToRoom(roomID, input.UserID, userID)
+	if err != nil {
+		respondRoomAdminError(c, err, "Failed to add user to room")
+		return
+	}
+	if !added {
+		c.JSON(http.StatusOK, gin.H{"message": "User is already in the room"})
+		return
+	}
 
-// 	users, err := h.RoomService.GetUsersInRoom(roomID)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users in room"})
-// 		return
-// 	}
+	c.JSON(http.StatusCreated, gin.H{"message": "User added successfully to room"})
+}
 
-// 	c.JSON(http.StatusOK, gin.H{"users": users})
-// }
+// RemoveUserFromRoom handles the DELETE request to remove a user from a room.
+// Members may remove themselves to leave the room.
+func (h *RoomHandler) RemoveUserFromRoom(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, memberID, ok := parseMemberParams(c)
+	if !ok {
+		return
+	}
+
+	err = h.RoomService.RemoveUserFromRoom(roomID, memberID, userID)
+	if err != nil {
+		respondRoomAdminError(c, err, "Failed to remove user from room")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User removed successfully from room"})
+}
+
+// GetUsersInRoom handles the GET request to retrieve the members of a room.
+func (h *RoomHandler) GetUsersInRoom(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	users, err := h.RoomService.GetRoomMembers(roomID, userID)
+	if err != nil {
+		respondRoomAdminError(c, err, "Failed to retrieve users in room")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
+
+// respondRoomAdminError writes the HTTP response for an error from a room administration call.
+func respondRoomAdminError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidRoomName),
+		errors.Is(err, services.ErrInvalidRoomRole),
+		errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotInRoom):
+		c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+	case errors.Is(err, services.ErrNotRoomAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room moderators and the owner can do this"})
+	case errors.Is(err, services.ErrNotRoomOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the room owner can do this"})
+	case errors.Is(err, services.ErrMemberOutranks):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoomNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+	case errors.Is(err, services.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrRoomOwnerRemoval), errors.Is(err, services.ErrConversationMembership):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// GetRoomPresence handles the GET request to retrieve the live status of a room's members.
+func (h *RoomHandler) GetRoomPresence(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	presence, err := h.RoomService.GetRoomPresence(roomID, userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotInRoom) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve presence"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"presence": presence})
+}
+
+// MarkRoomRead handles the POST request to mark a room as read up to a message.
+func (h *RoomHandler) MarkRoomRead(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	var input models.ReadRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	receipt, err := h.RoomService.MarkRoomRead(roomID, userID, input.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotInRoom):
+			c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+		case errors.Is(err, services.ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark room as read"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, receipt)
+}
+
+// GetMyRooms handles the GET request to list the caller's rooms with unread counts.
+func (h *RoomHandler) GetMyRooms(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	summaries, err := h.RoomService.GetRoomSummaries(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve rooms"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rooms": summaries})
+}
+
+// GetMyMentions handles the GET request for the caller's mention inbox, newest first.
+// Supports a before mention ID cursor, a limit and unread=true to skip read mentions.
+func (h *RoomHandler) GetMyMentions(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	before, errBefore := strconv.Atoi(c.DefaultQuery("before", "0"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if errBefore != nil || errLimit != nil || before < 0 || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	inbox, err := h.RoomService.GetMentionInbox(userID, before, limit, unreadOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve mentions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, inbox)
+}
+
+// MarkMentionsRead handles the POST request to mark mentions in the caller's inbox as read.
+func (h *RoomHandler) MarkMentionsRead(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var input models.MentionReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+			return
+		}
+	}
+
+	if err := h.RoomService.MarkMentionsRead(userID, input.IDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark mentions as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mentions marked as read"})
+}
 
 // // GetRoomsByUserID handles the GET request to retrieve all rooms a user is a member of.
 // func (h *RoomHandler) GetRoomsByUserID(c *gin.Context) {
@@ -262,40 +471,362 @@ func (h *RoomHandler) IsUserRoomAdmin(c *gin.Context) {
 // 	c.JSON(http.StatusOK, gin.H{"rooms": rooms})
 // }
 
-// // GetMessagesByRoomID handles the GET request to retrieve all messages in a room.
-// func (h *RoomHandler) GetMessagesByRoomID(c *gin.Context) {
-// 	roomID, err := strconv.Atoi(c.Param("id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
-// 		return
-// 	}
+// GetMessagesByRoomID handles the GET request to retrieve a page of messages in a room.
+// Supports the before/after message ID cursors and a limit query parameter.
+func (h *RoomHandler) GetMessagesByRoomID(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
 
-// 	messages, err := h.RoomService.GetMessagesByRoomID(roomID)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
-// 		return
-// 	}
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
 
-// 	c.JSON(http.StatusOK, gin.H{"messages": messages})
-// }
+	before, errBefore := strconv.Atoi(c.DefaultQuery("before", "0"))
+	after, errAfter := strconv.Atoi(c.DefaultQuery("after", "0"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if errBefore != nil || errAfter != nil || errLimit != nil || before < 0 || after < 0 || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
 
-// // DeleteMessageByID handles the DELETE request to remove a message from a chat room.
-// func (h *RoomHandler) DeleteMessageByID(c *gin.Context) {
-// 	messageID, err := strconv.Atoi(c.Param("message_id"))
-// 	if err != nil {
-// 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
-// 		return
-// 	}
+	room, err := h.RoomService.GetRoom(roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch room"})
+		return
+	}
+	if room == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Room not found"})
+		return
+	}
 
-// 	userID := c.GetInt("user_id")
-// 	err = h.RoomService.DeleteMessageByID(messageID, userID)
-// 	if err != nil {
-// 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
-// 		return
-// 	}
+	messages, hasMore, err := h.RoomService.GetMessagesByRoomID(roomID, userID, before, after, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotInRoom) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
+		return
+	}
 
-// 	c.JSON(http.StatusOK, gin.H{"message": "Message deleted successfully"})
-// }
+	users, err := h.RoomService.GetUsersInRoom(roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users in room"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.RoomResponse{
+		ID:          room.ID,
+		Name:        room.Name,
+		Description: room.Description,
+		CreatedBy:   room.CreatedBy,
+		Users:       users,
+		Messages:    messages,
+		HasMore:     hasMore,
+		CreatedAt:   room.CreatedAt,
+		UpdatedAt:   room.UpdatedAt,
+	})
+}
+
+// parseMemberParams reads the room and user IDs of a /rooms/:id/users/:userID route.
+func parseMemberParams(c *gin.Context) (int, int, bool) {
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return 0, 0, false
+	}
+
+	userID, err := strconv.Atoi(c.Param("userID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return 0, 0, false
+	}
+
+	return roomID, userID, true
+}
+
+// parseMessageParams reads the room and message IDs of a /rooms/:id/messages/:messageID route.
+func parseMessageParams(c *gin.Context) (int, int, bool) {
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return 0, 0, false
+	}
+
+	messageID, err := strconv.Atoi(c.Param("messageID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return 0, 0, false
+	}
+	return roomID, messageID, true
+}
+
+// respondMessageError maps message service errors to HTTP responses.
+func respondMessageError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrEmptyMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message content is empty"})
+	case errors.Is(err, services.ErrInvalidEmoji):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid emoji"})
+	case errors.Is(err, services.ErrUserNotInRoom):
+		c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+	case errors.Is(err, services.ErrNotMessageAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed to change this message"})
+	case errors.Is(err, services.ErrNotRoomAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only room moderators and the owner can do this"})
+	case errors.Is(err, services.ErrPinLimitReached):
+		c.JSON(http.StatusConflict, gin.H{"error": "Room has too many pinned messages"})
+	case errors.Is(err, services.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// EditMessage handles the PATCH request to change the content of the caller's own message.
+func (h *RoomHandler) EditMessage(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	var input models.MessageUpdateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	message, err := h.RoomService.EditMessage(roomID, messageID, userID, input.Content)
+	if err != nil {
+		respondMessageError(c, err, "Failed to edit message")
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// DeleteMessage handles the DELETE request to remove a message, leaving a tombstone.
+// Authors can delete their own messages; moderators and the owner can delete any message.
+func (h *RoomHandler) DeleteMessage(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	message, err := h.RoomService.DeleteMessage(roomID, messageID, userID)
+	if err != nil {
+		respondMessageError(c, err, "Failed to delete message")
+		return
+	}
+
+	c.JSON(http.StatusOK, message)
+}
+
+// GetMessageHistory handles the GET request for the previous versions of a message.
+func (h *RoomHandler) GetMessageHistory(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	edits, err := h.RoomService.GetMessageHistory(roomID, messageID, userID)
+	if err != nil {
+		respondMessageError(c, err, "Failed to retrieve message history")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"edits": edits})
+}
+
+// GetThread handles the GET request to retrieve a page of replies in a message's thread.
+// Supports the same before/after/limit query parameters as GetMessagesByRoomID.
+func (h *RoomHandler) GetThread(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	before, errBefore := strconv.Atoi(c.DefaultQuery("before", "0"))
+	after, errAfter := strconv.Atoi(c.DefaultQuery("after", "0"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	if errBefore != nil || errAfter != nil || errLimit != nil || before < 0 || after < 0 || limit < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pagination parameters"})
+		return
+	}
+
+	thread, err := h.RoomService.GetThread(roomID, messageID, userID, before, after, limit)
+	if err != nil {
+		respondMessageError(c, err, "Failed to retrieve thread")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// FollowThread handles the POST request to follow a message's thread.
+func (h *RoomHandler) FollowThread(c *gin.Context) {
+	h.setThreadFollow(c, true)
+}
+
+// UnfollowThread handles the DELETE request to stop following a message's thread.
+func (h *RoomHandler) UnfollowThread(c *gin.Context) {
+	h.setThreadFollow(c, false)
+}
+
+func (h *RoomHandler) setThreadFollow(c *gin.Context, follow bool) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.RoomService.SetThreadFollow(roomID, messageID, userID, follow); err != nil {
+		respondMessageError(c, err, "Failed to update thread follow status")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"following": follow})
+}
+
+// GetPinnedMessages handles the GET request to list the pinned messages of a room.
+func (h *RoomHandler) GetPinnedMessages(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	pins, err := h.RoomService.GetPinnedMessages(roomID, userID)
+	if err != nil {
+		respondMessageError(c, err, "Failed to retrieve pinned messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pins": pins})
+}
+
+// PinMessage handles the POST request to pin a message in its room.
+func (h *RoomHandler) PinMessage(c *gin.Context) {
+	h.setPin(c, true)
+}
+
+// UnpinMessage handles the DELETE request to unpin a message.
+func (h *RoomHandler) UnpinMessage(c *gin.Context) {
+	h.setPin(c, false)
+}
+
+func (h *RoomHandler) setPin(c *gin.Context, pin bool) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	if pin {
+		err = h.RoomService.PinMessage(roomID, messageID, userID)
+	} else {
+		err = h.RoomService.UnpinMessage(roomID, messageID, userID)
+	}
+	if err != nil {
+		respondMessageError(c, err, "Failed to update pin")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pinned": pin})
+}
+
+// AddReaction handles the POST request to react to a message with an emoji.
+func (h *RoomHandler) AddReaction(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	var input models.ReactionRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	if err := h.RoomService.AddReaction(roomID, messageID, userID, input.Emoji); err != nil {
+		respondMessageError(c, err, "Failed to add reaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction added successfully"})
+}
+
+// RemoveReaction handles the DELETE request to take back an emoji reaction to a message.
+func (h *RoomHandler) RemoveReaction(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, messageID, ok := parseMessageParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.RoomService.RemoveReaction(roomID, messageID, userID, c.Param("emoji")); err != nil {
+		respondMessageError(c, err, "Failed to remove reaction")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reaction removed successfully"})
+}
 
 // // IsUserInRoom handles the GET request to check if a user is in a room.
 // func (h *RoomHandler) IsUserInRoom(c *gin.Context) {
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduledMessageHandler handles HTTP requests for scheduled messages.
+type ScheduledMessageHandler struct {
+	ScheduledService *services.ScheduledMessageService
+}
+
+// NewScheduledMessageHandler creates a new ScheduledMessageHandler instance.
+func NewScheduledMessageHandler(service *services.ScheduledMessageService) *ScheduledMessageHandler {
+	return &ScheduledMessageHandler{ScheduledService: service}
+}
+
+// ScheduleMessage handles the POST request to schedule a message in a room.
+func (h *ScheduledMessageHandler) ScheduleMessage(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	var input models.ScheduledMessageRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	scheduled, err := h.ScheduledService.ScheduleMessage(roomID, userID, input.Content, input.ParentID, input.SendAt)
+	if err != nil {
+		respondScheduledError(c, err, "Failed to schedule message")
+		return
+	}
+
+	c.JSON(http.StatusCreated, scheduled)
+}
+
+// GetMyScheduledMessages handles the GET request to list the caller's pending scheduled messages.
+// Supports an optional room_id filter.
+func (h *ScheduledMessageHandler) GetMyScheduledMessages(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, err := strconv.Atoi(c.DefaultQuery("room_id", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid room ID"})
+		return
+	}
+
+	scheduled, err := h.ScheduledService.GetPendingMessages(userID, roomID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve scheduled messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scheduled_messages": scheduled})
+}
+
+// UpdateScheduledMessage handles the PATCH request to change a pending scheduled message.
+func (h *ScheduledMessageHandler) UpdateScheduledMessage(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled message ID"})
+		return
+	}
+
+	var input models.ScheduledMessageUpdateRequest
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input"})
+		return
+	}
+
+	scheduled, err := h.ScheduledService.UpdateScheduledMessage(id, userID, input.Content, input.SendAt)
+	if err != nil {
+		respondScheduledError(c, err, "Failed to update scheduled message")
+		return
+	}
+
+	c.JSON(http.StatusOK, scheduled)
+}
+
+// CancelScheduledMessage handles the DELETE request to cancel a pending scheduled message.
+func (h *ScheduledMessageHandler) CancelScheduledMessage(c *gin.Context) {
+	userID, _, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scheduled message ID"})
+		return
+	}
+
+	if err := h.ScheduledService.CancelScheduledMessage(id, userID); err != nil {
+		respondScheduledError(c, err, "Failed to cancel scheduled message")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled message cancelled successfully"})
+}
+
+// respondScheduledError maps scheduled message service errors to HTTP responses.
+func respondScheduledError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, services.ErrEmptyMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message content is empty"})
+	case errors.Is(err, services.ErrInvalidSendTime):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrUserNotInRoom):
+		c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+	case errors.Is(err, services.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Parent message not found"})
+	case errors.Is(err, services.ErrScheduledMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
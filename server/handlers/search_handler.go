@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"chatingApp/middleware"
+	"chatingApp/models"
+	"chatingApp/services"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SearchHandler handles HTTP requests for message search.
+type SearchHandler struct {
+	SearchService *services.SearchService
+}
+
+// NewSearchHandler creates a new SearchHandler instance.
+func NewSearchHandler(service *services.SearchService) *SearchHandler {
+	return &SearchHandler{SearchService: service}
+}
+
+// SearchMessages handles the GET request to search messages by text.
+// Supports room_id, user_id, from and to filters, limit/offset paging, and
+// global=true for super-admins to search every room.
+func (h *SearchHandler) SearchMessages(c *gin.Context) {
+	userID, role, _, err := middleware.ExtractTokenData(c, "user")
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	roomID, errRoom := strconv.Atoi(c.DefaultQuery("room_id", "0"))
+	authorID, errAuthor := strconv.Atoi(c.DefaultQuery("user_id", "0"))
+	limit, errLimit := strconv.Atoi(c.DefaultQuery("limit", "0"))
+	offset, errOffset := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if errRoom != nil || errAuthor != nil || errLimit != nil || errOffset != nil || limit < 0 || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid search parameters"})
+		return
+	}
+
+	from, errFrom := parseTimeBound(c.Query("from"), false)
+	to, errTo := parseTimeBound(c.Query("to"), true)
+	if errFrom != nil || errTo != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range, use RFC 3339 or YYYY-MM-DD"})
+		return
+	}
+
+	query := models.MessageSearchQuery{
+		Query:  c.Query("q"),
+		RoomID: roomID,
+		UserID: authorID,
+		From:   from,
+		To:     to,
+		Global: c.Query("global") == "true",
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	results, err := h.SearchService.SearchMessages(query, userID, role)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmptySearchQuery):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing search query"})
+		case errors.Is(err, services.ErrGlobalSearchForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "Global search is limited to super-admins"})
+		case errors.Is(err, services.ErrUserNotInRoom):
+			c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search messages"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+// parseTimeBound reads an RFC 3339 time or a YYYY-MM-DD date, which is a UTC day. A date
+// used as the end of a range includes that whole day.
+func parseTimeBound(value string, endOfRange bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfRange {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
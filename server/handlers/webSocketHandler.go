@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"chatingApp/hub"
 	"chatingApp/services"
 	"log"
 	"net/http"
 	"strconv"
-	"sync"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 )
@@ -13,15 +15,14 @@ import (
 // WebSocketHandler handles WebSocket connections for chat rooms.
 type WebSocketHandler struct {
 	RoomService *services.RoomService
-	Clients     map[int]map[*websocket.Conn]bool // roomID -> set of WebSocket connections
-	Mutex       sync.Mutex
+	Hub         *hub.Hub // Per-room fan-out of outbound frames
 }
 
 // NewWebSocketHandler creates a new WebSocketHandler instance.
-func NewWebSocketHandler(service *services.RoomService) *WebSocketHandler {
+func NewWebSocketHandler(service *services.RoomService, chatHub *hub.Hub) *WebSocketHandler {
 	return &WebSocketHandler{
 		RoomService: service,
-		Clients:     make(map[int]map[*websocket.Conn]bool),
+		Hub:         chatHub,
 	}
 }
 
@@ -31,6 +32,9 @@ var upgrader = websocket.Upgrader{
 }
 
 // HandleWebSocketConnection manages WebSocket connections per room.
+// The sender is always the authenticated user; only room members may connect.
+// Frames in both directions are models.Envelope events. An optional last_seq query
+// parameter resumes the connection after the last message the client saw.
 func (h *WebSocketHandler) HandleWebSocketConnection(c *gin.Context) {
 	roomID, err := strconv.Atoi(c.Param("roomID"))
 	if err != nil {
@@ -38,70 +42,78 @@ func (h *WebSocketHandler) HandleWebSocketConnection(c *gin.Context) {
 		return
 	}
 
-	// userID := c.GetInt("user_id") // Fetch user ID from middleware
-	// if !h.RoomService.IsUserInRoom(roomID, userID) {
-	// 	c.JSON(http.StatusForbidden, gin.H{"error": "User not in room"})
-	// 	return
-	// }
+	lastSeq := int64(-1)
+	if value := c.Query("last_seq"); value != "" {
+		lastSeq, err = strconv.ParseInt(value, 10, 64)
+		if err != nil || lastSeq < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid last_seq"})
+			return
+		}
+	}
+
+	userID := c.GetInt("userID") // Set by AuthMiddleware from the JWT claims
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token data: user ID missing or invalid"})
+		return
+	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Println("❌ WebSocket Upgrade Failed:", err)
 		return
 	}
-	defer conn.Close()
 
-	h.Mutex.Lock()
-	if _, exists := h.Clients[roomID]; !exists {
-		h.Clients[roomID] = make(map[*websocket.Conn]bool)
+	// Browsers cannot read the status of a failed handshake, so reject with a close code instead
+	if !h.RoomService.Permissions.IsMember(roomID, userID) {
+		log.Printf("❌ WebSocket Rejected, user not in room (Room: %d, User: %d)\n", roomID, userID)
+		rejectConnection(conn, hub.CloseNotRoomMember, "not a member of this room")
+		return
 	}
-	h.Clients[roomID][conn] = true
-	h.Mutex.Unlock()
 
-	// log.Printf("✅ WebSocket Connection Established (Room: %d, User: %d)\n", roomID, userID)
+	client := h.Hub.NewClient(conn, userID)
+	go client.WritePump()
+	log.Printf("✅ WebSocket Connection Established (Room: %d, User: %d)\n", roomID, userID)
 
-	for {
-		var msg struct {
-			UserID  int    `json:"user_id"`
-			Content string `json:"content"`
-		}
+	// A reconnecting client passes the last sequence number it saw to get the gap replayed
+	session := newSession(h, client, userID, roomID)
+	session.subscribe(roomID, "", lastSeq)
+	session.run()
+	log.Printf("❌ WebSocket Disconnected (Room: %d, User: %d)\n", roomID, userID)
+}
 
-		if err := conn.ReadJSON(&msg); err != nil {
-			log.Println("❌ WebSocket Read Error:", err)
-			break
-		}
+// HandleMultiplexedConnection manages a WebSocket connection that carries many rooms.
+// The client subscribes to and unsubscribes from rooms with control frames, and every
+// event it receives is tagged with its room_id.
+func (h *WebSocketHandler) HandleMultiplexedConnection(c *gin.Context) {
+	userID := c.GetInt("userID") // Set by AuthMiddleware from the JWT claims
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token data: user ID missing or invalid"})
+		return
+	}
 
-		// log.Printf("📨 Message Received (Room: %d, User: %d): %s\n", roomID, msg.UserID, msg.Content)
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Println("❌ WebSocket Upgrade Failed:", err)
+		return
+	}
 
-		// Save message in database
-		// err := h.RoomService.AddMessageToRoom(roomID, msg.UserID, msg.Content)
-		// if err != nil {
-		// 	log.Println("❌ Failed to save message:", err)
-		// 	continue
-		// }
+	client := h.Hub.NewClient(conn, userID)
+	client.Multiplexed = true
+	go client.WritePump()
+	log.Printf("✅ Multiplexed WebSocket Connection Established (User: %d)\n", userID)
 
-		// Broadcast message to all clients in the room
-		h.broadcastMessage(roomID, msg.UserID, msg.Content)
-	}
+	newSession(h, client, userID, 0).run()
+	log.Printf("❌ Multiplexed WebSocket Disconnected (User: %d)\n", userID)
+}
 
-	// Cleanup on disconnect
-	h.Mutex.Lock()
-	delete(h.Clients[roomID], conn)
-	h.Mutex.Unlock()
-	// log.Printf("❌ WebSocket Disconnected (Room: %d, User: %d)\n", roomID, userID)
+// GetStats handles the GET request for WebSocket connection counters, including reaped connections.
+func (h *WebSocketHandler) GetStats(c *gin.Context) {
+	c.JSON(http.StatusOK, h.Hub.Stats())
 }
 
-// Broadcast message to all WebSocket clients in a room.
-func (h *WebSocketHandler) broadcastMessage(roomID, senderID int, message string) {
-	h.Mutex.Lock()
-	defer h.Mutex.Unlock()
-
-	for client := range h.Clients[roomID] {
-		err := client.WriteJSON(gin.H{"room_id": roomID, "user_id": senderID, "content": message})
-		if err != nil {
-			log.Println("❌ WebSocket Write Error:", err)
-			client.Close()
-			delete(h.Clients[roomID], client)
-		}
-	}
+// rejectConnection closes a freshly upgraded connection with an application close code.
+func rejectConnection(conn *websocket.Conn, code int, reason string) {
+	msg := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	conn.Close()
 }
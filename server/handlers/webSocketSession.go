@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"chatingApp/config"
+	"chatingApp/hub"
+	"chatingApp/models"
+	"chatingApp/services"
+	"encoding/json"
+	"errors"
+	"log"
+)
+
+// maxSubscriptions is how many rooms one connection may subscribe to at once.
+const maxSubscriptions = 100
+
+// wsSession is the server side of one authenticated WebSocket connection.
+type wsSession struct {
+	handler       *WebSocketHandler
+	client        *hub.Client
+	userID        int
+	defaultRoomID int          // Room of a /ws/:roomID connection, used when a frame names none
+	rooms         map[int]bool // Rooms the client is subscribed to
+}
+
+// newSession creates the session for an upgraded connection.
+func newSession(h *WebSocketHandler, client *hub.Client, userID, defaultRoomID int) *wsSession {
+	return &wsSession{
+		handler:       h,
+		client:        client,
+		userID:        userID,
+		defaultRoomID: defaultRoomID,
+		rooms:         make(map[int]bool),
+	}
+}
+
+// run reads and handles frames until the connection ends, then leaves every subscribed room.
+// The user's presence follows the connection.
+func (s *wsSession) run() {
+	if status, changed := s.handler.Hub.Presence.Connect(s.client); changed {
+		s.handler.RoomService.BroadcastPresence(s.userID, status)
+	}
+
+	for {
+		frame, err := s.client.ReadFrame()
+		if err != nil {
+			log.Println("❌ WebSocket Read Error:", err)
+			break
+		}
+
+		if !s.handleFrame(frame) {
+			break
+		}
+	}
+
+	// Cleanup on disconnect
+	for roomID := range s.rooms {
+		s.unsubscribe(roomID)
+	}
+	s.client.Close()
+
+	if status, changed := s.handler.Hub.Presence.Disconnect(s.client); changed {
+		s.handler.RoomService.BroadcastPresence(s.userID, status)
+	}
+}
+
+// handleFrame decodes one inbound frame and dispatches it by event type.
+// It returns false when the connection should be closed.
+func (s *wsSession) handleFrame(frame []byte) bool {
+	s.dropRevoked()
+
+	var env models.Envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		s.client.SendError(0, "", models.ErrCodeInvalidFrame, "frame is not a valid event envelope")
+		return true
+	}
+
+	// A missing version is read as the current one
+	if env.Version != 0 && env.Version != models.ProtocolVersion {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeUnsupportedVersion, "unsupported protocol version")
+		return true
+	}
+
+	// Presence belongs to the user, not to a room
+	if env.Type == models.EventPresence {
+		s.handlePresence(env)
+		return true
+	}
+
+	// Frames without a room target the room of the connection, if it has one
+	if env.RoomID == 0 {
+		env.RoomID = s.defaultRoomID
+	}
+	if env.RoomID == 0 {
+		s.client.SendError(0, env.ID, models.ErrCodeMissingRoom, "room_id is required")
+		return true
+	}
+
+	switch env.Type {
+	case models.EventMessageSend:
+		return s.handleMessageSend(env)
+	case models.EventSubscribe:
+		s.handleSubscribe(env)
+		return true
+	case models.EventTyping:
+		s.handleTyping(env)
+		return true
+	case models.EventRead:
+		s.handleRead(env)
+		return true
+	case models.EventUnsubscribe:
+		s.unsubscribe(env.RoomID)
+		s.client.SendEvent(env.RoomID, models.EventUnsubscribed, env.ID, models.UnsubscribedPayload{})
+		return true
+	default:
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeUnknownType, "unknown event type: "+env.Type)
+		return true
+	}
+}
+
+// handleSubscribe checks room membership and subscribes the client to the room.
+func (s *wsSession) handleSubscribe(env models.Envelope) {
+	var payload models.SubscribePayload
+	if len(env.Payload) > 0 {
+		if err := json.Unmarshal(env.Payload, &payload); err != nil {
+			s.client.SendError(env.RoomID, env.ID, models.ErrCodeInvalidPayload, "invalid subscribe payload")
+			return
+		}
+	}
+
+	if !s.handler.RoomService.Permissions.IsMember(env.RoomID, s.userID) {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeNotRoomMember, services.ErrUserNotInRoom.Error())
+		return
+	}
+
+	if s.rooms[env.RoomID] {
+		// Already subscribed: resubscribing would register the client twice
+		s.unsubscribe(env.RoomID)
+	}
+	if len(s.rooms) >= maxSubscriptions {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeTooManyRooms, "too many room subscriptions")
+		return
+	}
+
+	lastSeq := int64(-1)
+	if payload.LastSeq != nil && *payload.LastSeq >= 0 {
+		lastSeq = *payload.LastSeq
+	}
+	s.subscribe(env.RoomID, env.ID, lastSeq)
+}
+
+// subscribe joins the client to a room and confirms it. With lastSeq >= 0 the messages
+// missed after it are replayed first; live events are held back until that is done.
+// A client too far behind is told to refetch over REST instead.
+func (s *wsSession) subscribe(roomID int, id string, lastSeq int64) {
+	s.client.Hold()
+	s.handler.Hub.Register(roomID, s.client)
+	s.rooms[roomID] = true
+
+	var replayedSeq int64
+	defer func() { s.client.Release(roomID, replayedSeq) }()
+
+	if lastSeq < 0 {
+		latest, err := s.handler.RoomService.GetRoomLastSeq(roomID)
+		if err != nil {
+			s.client.SendError(roomID, id, models.ErrCodeInternal, "failed to subscribe to room")
+			return
+		}
+		s.client.SendEventWait(roomID, models.EventSubscribed, id, models.SubscribedPayload{LastSeq: latest})
+		return
+	}
+
+	messages, latest, err := s.handler.RoomService.GetMessagesSinceSeq(roomID, s.userID, lastSeq, config.AppConfig.WSResumeMaxGap)
+	if err != nil && !errors.Is(err, services.ErrResumeGapTooLarge) {
+		s.client.SendError(roomID, id, models.ErrCodeInternal, "failed to replay missed messages")
+		return
+	}
+
+	s.client.SendEventWait(roomID, models.EventSubscribed, id, models.SubscribedPayload{LastSeq: latest})
+	if err != nil {
+		s.client.SendEventWait(roomID, models.EventResyncRequired, "", models.ResyncRequiredPayload{LastSeq: latest})
+		return
+	}
+
+	for i := range messages {
+		if !s.client.SendEventWait(roomID, messages[i].EventType(), "", messages[i]) {
+			return
+		}
+		replayedSeq = messages[i].Seq
+	}
+}
+
+// unsubscribe removes the client from a room it is subscribed to.
+func (s *wsSession) unsubscribe(roomID int) {
+	if !s.rooms[roomID] {
+		return
+	}
+	s.handler.Hub.Unregister(roomID, s.client)
+	s.handler.Hub.Typing.Stop(roomID, s.userID)
+	delete(s.rooms, roomID)
+}
+
+// dropRevoked ends the subscriptions of rooms the user was removed from since the last frame.
+func (s *wsSession) dropRevoked() {
+	for _, roomID := range s.client.TakeRevoked() {
+		s.unsubscribe(roomID)
+	}
+}
+
+// handlePresence sets the status of this connection to online or away.
+func (s *wsSession) handlePresence(env models.Envelope) {
+	var payload models.PresencePayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		s.client.SendError(0, env.ID, models.ErrCodeInvalidPayload, "invalid presence payload")
+		return
+	}
+	if payload.Status != models.PresenceOnline && payload.Status != models.PresenceAway {
+		s.client.SendError(0, env.ID, models.ErrCodeInvalidStatus, "status must be online or away")
+		return
+	}
+
+	if status, changed := s.handler.Hub.Presence.SetStatus(s.client, payload.Status); changed {
+		s.handler.RoomService.BroadcastPresence(s.userID, status)
+	}
+}
+
+// handleTyping starts or stops the user's typing indicator in a subscribed room.
+func (s *wsSession) handleTyping(env models.Envelope) {
+	var payload models.TypingPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeInvalidPayload, "invalid typing payload")
+		return
+	}
+	if !s.rooms[env.RoomID] {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeNotSubscribed, "not subscribed to the room")
+		return
+	}
+	// A kick may not have reached this instance yet, so membership is checked as well
+	if !s.handler.RoomService.Permissions.IsMember(env.RoomID, s.userID) {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeNotRoomMember, services.ErrUserNotInRoom.Error())
+		return
+	}
+
+	if payload.Typing {
+		s.handler.Hub.Typing.Start(env.RoomID, s.userID)
+	} else {
+		s.handler.Hub.Typing.Stop(env.RoomID, s.userID)
+	}
+}
+
+// handleRead moves the user's read marker in a room; members get a read.receipt if it moved.
+func (s *wsSession) handleRead(env models.Envelope) {
+	var payload models.ReadPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil || payload.MessageID <= 0 {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeInvalidPayload, "invalid read payload")
+		return
+	}
+
+	_, err := s.handler.RoomService.MarkRoomRead(env.RoomID, s.userID, payload.MessageID)
+	switch {
+	case errors.Is(err, services.ErrUserNotInRoom):
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeNotRoomMember, err.Error())
+	case errors.Is(err, services.ErrMessageNotFound):
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeMessageNotFound, err.Error())
+	case err != nil:
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeInternal, "failed to mark room as read")
+	}
+}
+
+// handleMessageSend stores a message, acknowledges it to the sender and broadcasts it to the room.
+func (s *wsSession) handleMessageSend(env models.Envelope) bool {
+	var payload models.MessageSendPayload
+	if err := json.Unmarshal(env.Payload, &payload); err != nil {
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeInvalidPayload, "invalid message.send payload")
+		return true
+	}
+
+	// Save message in database before anyone sees it
+	message, err := s.handler.RoomService.AddMessageToRoom(env.RoomID, s.userID, payload.Content, payload.ParentID, payload.AttachmentIDs)
+	switch {
+	case errors.Is(err, services.ErrEmptyMessage):
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeEmptyMessage, err.Error())
+		return true
+	case errors.Is(err, services.ErrMessageNotFound):
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeMessageNotFound, "parent message not found")
+		return true
+	case errors.Is(err, services.ErrAttachmentUnavailable), errors.Is(err, services.ErrTooManyAttachments):
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeAttachment, err.Error())
+		return true
+	case errors.Is(err, services.ErrUserNotInRoom):
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeNotRoomMember, err.Error())
+		if env.RoomID == s.defaultRoomID && !s.client.Multiplexed {
+			// Membership of the connection's room was revoked while the socket was open
+			s.client.CloseWith(hub.CloseRemovedFromRoom, "removed from room")
+			return false
+		}
+		if s.rooms[env.RoomID] {
+			s.unsubscribe(env.RoomID)
+			s.client.SendEvent(env.RoomID, models.EventUnsubscribed, "", models.UnsubscribedPayload{
+				Code:   hub.CloseRemovedFromRoom,
+				Reason: "removed from room",
+			})
+		}
+		return true
+	case err != nil:
+		log.Println("❌ Failed to save message:", err)
+		s.client.SendError(env.RoomID, env.ID, models.ErrCodeInternal, "failed to save message")
+		return true
+	}
+
+	s.client.SendEvent(env.RoomID, models.EventMessageAck, env.ID, models.MessageAckPayload{
+		MessageID: message.ID,
+		Seq:       message.Seq,
+		CreatedAt: message.CreatedAt,
+	})
+
+	// Broadcast message to all clients in the room; sending ends the sender's typing indicator
+	s.handler.Hub.Typing.Stop(env.RoomID, s.userID)
+	s.handler.Hub.BroadcastSeqEvent(env.RoomID, message.Seq, message.EventType(), message)
+	return true
+}
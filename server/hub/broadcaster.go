@@ -0,0 +1,60 @@
+package hub
+
+import "encoding/json"
+
+// Event is one unit of fan-out carried between server instances.
+type Event struct {
+	RoomID int             `json:"room_id"`
+	Seq    int64           `json:"seq,omitempty"`   // Room sequence number of the message the frame carries, if any
+	Frame  json.RawMessage `json:"frame,omitempty"` // Encoded frame for every client in the room
+	Kick   *Kick           `json:"kick,omitempty"`  // Disconnect one user's clients in the room instead
+	// UserIDs sends the frame to every connection of these users instead of to the room,
+	// whether or not they are subscribed to it.
+	UserIDs []int `json:"user_ids,omitempty"`
+}
+
+// Kick asks a room to disconnect every client of one user.
+type Kick struct {
+	UserID int    `json:"user_id"`
+	Code   int    `json:"code"`
+	Reason string `json:"reason"`
+}
+
+// Broadcaster carries hub events to every server instance, this one included.
+type Broadcaster interface {
+	// Subscribe sets the function that delivers events to this instance's clients.
+	// It is called once, before the first Publish.
+	Subscribe(deliver func(Event)) error
+	// Publish sends an event to the subscribers on every instance.
+	Publish(event Event) error
+	// Close stops receiving events.
+	Close() error
+}
+
+// MemoryBroadcaster delivers events within a single process. It is the default
+// when only one server instance is running.
+type MemoryBroadcaster struct {
+	deliver func(Event)
+}
+
+// NewMemoryBroadcaster creates a single-node Broadcaster.
+func NewMemoryBroadcaster() *MemoryBroadcaster {
+	return &MemoryBroadcaster{}
+}
+
+// Subscribe sets the local delivery function.
+func (b *MemoryBroadcaster) Subscribe(deliver func(Event)) error {
+	b.deliver = deliver
+	return nil
+}
+
+// Publish delivers the event to local clients straight away.
+func (b *MemoryBroadcaster) Publish(event Event) error {
+	b.deliver(event)
+	return nil
+}
+
+// Close is a no-op for the in-memory broadcaster.
+func (b *MemoryBroadcaster) Close() error {
+	return nil
+}
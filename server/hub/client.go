@@ -0,0 +1,240 @@
+package hub
+
+import (
+	"chatingApp/models"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// sendBufferSize is how many outbound frames a client may have queued before it counts as slow.
+	sendBufferSize = 64
+	// closeWait bounds how long sending the close frame may take.
+	closeWait = time.Second
+	// maxHeldEvents is how many live events a client may hold back while it is being caught up.
+	maxHeldEvents = 1024
+)
+
+// Application close codes sent to WebSocket clients (4000-4999 is the private range).
+const (
+	// CloseNotRoomMember rejects a connection from a user who is not a member of the room.
+	CloseNotRoomMember = 4403
+	// CloseRemovedFromRoom closes a connection whose user was removed from the room.
+	CloseRemovedFromRoom = 4410
+)
+
+// Client is a single WebSocket connection with its own outbound queue.
+// Frames are written by a dedicated goroutine, so a stalled socket only delays itself.
+type Client struct {
+	UserID int
+	// Multiplexed clients subscribe to rooms with control frames; being kicked
+	// from one room ends that subscription instead of closing the socket.
+	Multiplexed bool
+
+	hub       *Hub
+	conn      *websocket.Conn
+	send      chan []byte
+	done      chan struct{}
+	closeOnce sync.Once
+
+	holdMu  sync.Mutex
+	holding bool    // Live events are held back while missed messages are replayed
+	held    []Event // Events received while holding, in arrival order
+
+	revokedMu sync.Mutex
+	revoked   []int // Rooms whose subscription a kick ended, not yet taken by the reader
+}
+
+// NewClient wraps an upgraded WebSocket connection for the given user and
+// applies the hub's read limit and heartbeat deadline to it.
+func (h *Hub) NewClient(conn *websocket.Conn, userID int) *Client {
+	client := &Client{
+		UserID: userID,
+		hub:    h,
+		conn:   conn,
+		send:   make(chan []byte, sendBufferSize),
+		done:   make(chan struct{}),
+	}
+
+	conn.SetReadLimit(h.config.MaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(h.config.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(h.config.PongWait))
+	})
+
+	h.connections.Add(1)
+	h.addClient(client)
+	return client
+}
+
+// ReadFrame blocks until the next inbound frame arrives. Any frame, like a pong,
+// counts as a heartbeat. A connection that stays silent past the pong wait is reaped.
+func (c *Client) ReadFrame() ([]byte, error) {
+	_, frame, err := c.conn.ReadMessage()
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			c.hub.reaped.Add(1)
+			log.Printf("💀 WebSocket missed heartbeat, reaping (User: %d)\n", c.UserID)
+		}
+		return nil, err
+	}
+
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.config.PongWait))
+	return frame, nil
+}
+
+// Send queues a frame for this client only. It returns false if the client
+// is closed or its buffer is full.
+func (c *Client) Send(frame []byte) bool {
+	return c.enqueue(frame)
+}
+
+// Hold starts holding back live room events, so missed messages can be replayed
+// ahead of them. Call it before registering the client with a room.
+func (c *Client) Hold() {
+	c.holdMu.Lock()
+	c.holding = true
+	c.holdMu.Unlock()
+}
+
+// Release queues the events held since Hold and resumes live delivery. Held events
+// for messages of roomID up to replayedSeq were already replayed and are dropped. It
+// waits for room in the outbound queue, flushing in batches so no room is ever blocked.
+func (c *Client) Release(roomID int, replayedSeq int64) {
+	for {
+		c.holdMu.Lock()
+		batch := c.held
+		c.held = nil
+		if len(batch) == 0 {
+			c.holding = false
+			c.holdMu.Unlock()
+			return
+		}
+		c.holdMu.Unlock()
+
+		for _, event := range batch {
+			if event.RoomID == roomID && event.Seq > 0 && event.Seq <= replayedSeq {
+				continue
+			}
+			select {
+			case c.send <- event.Frame:
+			case <-c.done:
+				return
+			}
+		}
+	}
+}
+
+// deliver hands a room event to the client, holding it back while the client is being caught up.
+func (c *Client) deliver(event Event) bool {
+	c.holdMu.Lock()
+	defer c.holdMu.Unlock()
+
+	if c.holding {
+		if len(c.held) >= maxHeldEvents {
+			return false
+		}
+		c.held = append(c.held, event)
+		return true
+	}
+	return c.enqueue(event.Frame)
+}
+
+// revoke ends the client's membership of a room after a kick, either by closing the
+// socket or, for multiplexed clients, by telling it the subscription is gone. The room
+// is also recorded for TakeRevoked, so the reader drops the subscription on its side.
+func (c *Client) revoke(roomID int, k Kick) {
+	if !c.Multiplexed {
+		c.CloseWith(k.Code, k.Reason)
+		return
+	}
+
+	c.revokedMu.Lock()
+	c.revoked = append(c.revoked, roomID)
+	c.revokedMu.Unlock()
+	c.SendEvent(roomID, models.EventUnsubscribed, "", models.UnsubscribedPayload{Code: k.Code, Reason: k.Reason})
+}
+
+// TakeRevoked returns the rooms the client was kicked from since the last call. The
+// caller still has to Unregister the client from them.
+func (c *Client) TakeRevoked() []int {
+	c.revokedMu.Lock()
+	defer c.revokedMu.Unlock()
+	revoked := c.revoked
+	c.revoked = nil
+	return revoked
+}
+
+// enqueue adds a frame to the outbound queue without blocking.
+func (c *Client) enqueue(frame []byte) bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+	}
+
+	select {
+	case c.send <- frame:
+		return true
+	default:
+		return false
+	}
+}
+
+// WritePump writes queued frames and periodic pings to the connection until the client is closed.
+func (c *Client) WritePump() {
+	ticker := time.NewTicker(c.hub.config.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case frame := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, frame); err != nil {
+				log.Println("❌ WebSocket Write Error:", err)
+				c.Close()
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.config.WriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Println("❌ WebSocket Ping Error:", err)
+				c.Close()
+				return
+			}
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Done returns a channel that is closed once the client has been closed.
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Close disconnects the client with a normal close code.
+func (c *Client) Close() {
+	c.CloseWith(websocket.CloseNormalClosure, "")
+}
+
+// CloseWith disconnects the client with the given close code and reason.
+// It is safe to call more than once and never blocks the caller.
+func (c *Client) CloseWith(code int, reason string) {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.hub.connections.Add(-1)
+		c.hub.removeClient(c)
+		go func() {
+			msg := websocket.FormatCloseMessage(code, reason)
+			_ = c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeWait))
+			c.conn.Close()
+		}()
+	})
+}
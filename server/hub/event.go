@@ -0,0 +1,95 @@
+package hub
+
+import (
+	"chatingApp/models"
+	"encoding/json"
+	"log"
+)
+
+// EncodeEvent wraps a payload in a versioned envelope tagged with its room and encodes it as a frame.
+// id is the client-chosen ID being answered, or empty for server-initiated events.
+func EncodeEvent(roomID int, eventType, id string, payload interface{}) ([]byte, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(models.Envelope{
+		Version: models.ProtocolVersion,
+		Type:    eventType,
+		ID:      id,
+		RoomID:  roomID,
+		Payload: data,
+	})
+}
+
+// BroadcastEvent encodes an event once and broadcasts it to every client in a room.
+func (h *Hub) BroadcastEvent(roomID int, eventType string, payload interface{}) error {
+	frame, err := EncodeEvent(roomID, eventType, "", payload)
+	if err != nil {
+		log.Println("❌ Error: Failed to encode WebSocket event", err)
+		return err
+	}
+	h.Broadcast(roomID, frame)
+	return nil
+}
+
+// BroadcastSeqEvent is BroadcastEvent for an event carrying the room message with sequence
+// number seq. Clients resuming from a sequence number skip it if it was already replayed.
+func (h *Hub) BroadcastSeqEvent(roomID int, seq int64, eventType string, payload interface{}) error {
+	frame, err := EncodeEvent(roomID, eventType, "", payload)
+	if err != nil {
+		log.Println("❌ Error: Failed to encode WebSocket event", err)
+		return err
+	}
+	h.publish(Event{RoomID: roomID, Seq: seq, Frame: frame})
+	return nil
+}
+
+// SendToUsers encodes an event about a room once and sends it to every connection of
+// the given users, on every instance, whether or not they are subscribed to the room.
+func (h *Hub) SendToUsers(userIDs []int, roomID int, eventType string, payload interface{}) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	frame, err := EncodeEvent(roomID, eventType, "", payload)
+	if err != nil {
+		log.Println("❌ Error: Failed to encode WebSocket event", err)
+		return err
+	}
+	h.publish(Event{RoomID: roomID, Frame: frame, UserIDs: userIDs})
+	return nil
+}
+
+// SendEvent encodes an event about a room and queues it for this client only.
+func (c *Client) SendEvent(roomID int, eventType, id string, payload interface{}) bool {
+	frame, err := EncodeEvent(roomID, eventType, id, payload)
+	if err != nil {
+		log.Println("❌ Error: Failed to encode WebSocket event", err)
+		return false
+	}
+	return c.Send(frame)
+}
+
+// SendError queues an error event for this client only. roomID is the room the failed frame targeted, if any.
+func (c *Client) SendError(roomID int, id, code, message string) bool {
+	return c.SendEvent(roomID, models.EventError, id, models.ErrorPayload{Code: code, Message: message})
+}
+
+// SendEventWait is SendEvent that waits for room in the outbound queue instead of
+// giving up when it is full. It is meant for bulk sends such as replays.
+func (c *Client) SendEventWait(roomID int, eventType, id string, payload interface{}) bool {
+	frame, err := EncodeEvent(roomID, eventType, id, payload)
+	if err != nil {
+		log.Println("❌ Error: Failed to encode WebSocket event", err)
+		return false
+	}
+
+	select {
+	case c.send <- frame:
+		return true
+	case <-c.done:
+		return false
+	}
+}
@@ -0,0 +1,202 @@
+package hub
+
+import (
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// roomBroadcastBuffer is how many frames a room can queue before publishers wait on it.
+const roomBroadcastBuffer = 256
+
+// Config holds the heartbeat and size limits applied to every client connection.
+type Config struct {
+	PingInterval   time.Duration // How often each connection is pinged
+	PongWait       time.Duration // Silence allowed before a connection is reaped
+	WriteWait      time.Duration // Deadline for writing a single frame
+	MaxMessageSize int64         // Largest inbound frame in bytes
+}
+
+// Stats is a snapshot of the hub's connection counters.
+type Stats struct {
+	ActiveRooms int   `json:"active_rooms"`
+	Connections int64 `json:"connections"`
+	Reaped      int64 `json:"reaped"` // Connections closed for missing heartbeats since startup
+}
+
+// Hub routes frames to the WebSocket clients of each active room.
+// Every room with at least one connected client is served by its own goroutine,
+// so a busy or slow room never holds up delivery in another one.
+type Hub struct {
+	config      Config
+	broadcaster Broadcaster
+
+	mu    sync.Mutex
+	rooms map[int]*room
+	users map[int]map[*Client]bool // Open connections of each user on this instance
+
+	connections atomic.Int64
+	reaped      atomic.Int64
+
+	Presence *Presence // Status of users, across instances when given a PresenceStore
+	Typing   *Typing   // Ephemeral typing indicators
+}
+
+// NewHub creates an empty Hub whose clients use the given limits. Every broadcast
+// goes through broadcaster, so clients connected to other instances receive it too.
+// presenceStore shares presence with the other instances; nil keeps it to this one.
+func NewHub(config Config, broadcaster Broadcaster, presenceStore PresenceStore) (*Hub, error) {
+	h := &Hub{
+		config:      config,
+		broadcaster: broadcaster,
+		rooms:       make(map[int]*room),
+		users:       make(map[int]map[*Client]bool),
+	}
+	h.Presence = newPresence(presenceStore)
+	h.Typing = newTyping(h)
+	if err := broadcaster.Subscribe(h.deliver); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Stats returns the current connection counters.
+func (h *Hub) Stats() Stats {
+	h.mu.Lock()
+	activeRooms := len(h.rooms)
+	h.mu.Unlock()
+
+	return Stats{
+		ActiveRooms: activeRooms,
+		Connections: h.connections.Load(),
+		Reaped:      h.reaped.Load(),
+	}
+}
+
+// Register adds a client to a room, starting the room's goroutine if it is not running.
+func (h *Hub) Register(roomID int, client *Client) {
+	h.mu.Lock()
+	r, exists := h.rooms[roomID]
+	if !exists {
+		r = newRoom(roomID)
+		h.rooms[roomID] = r
+		go r.run()
+	}
+	r.members++
+	h.mu.Unlock()
+
+	r.register <- client
+}
+
+// Unregister removes a client from a room and stops the room's goroutine once it is empty.
+func (h *Hub) Unregister(roomID int, client *Client) {
+	h.mu.Lock()
+	r, exists := h.rooms[roomID]
+	if !exists {
+		h.mu.Unlock()
+		return
+	}
+	r.members--
+	last := r.members == 0
+	if last {
+		delete(h.rooms, roomID)
+	}
+	h.mu.Unlock()
+
+	r.unregister <- client
+	if last {
+		close(r.done)
+	}
+}
+
+// Broadcast publishes an encoded frame to every client in a room, on every instance.
+func (h *Hub) Broadcast(roomID int, frame []byte) {
+	h.publish(Event{RoomID: roomID, Frame: frame})
+}
+
+// publish hands an event to the broadcaster, logging failures.
+func (h *Hub) publish(event Event) {
+	if err := h.broadcaster.Publish(event); err != nil {
+		log.Println("❌ Error: Failed to publish room event", err)
+	}
+}
+
+// DisconnectUser closes every connection a user has open to a room, on every instance,
+// with the given close code.
+func (h *Hub) DisconnectUser(roomID, userID, code int, reason string) {
+	kick := &Kick{UserID: userID, Code: code, Reason: reason}
+	if err := h.broadcaster.Publish(Event{RoomID: roomID, Kick: kick}); err != nil {
+		log.Println("❌ Error: Failed to publish disconnect event", err)
+	}
+}
+
+// addClient records an open connection of a user.
+func (h *Hub) addClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.users[client.UserID] == nil {
+		h.users[client.UserID] = make(map[*Client]bool)
+	}
+	h.users[client.UserID][client] = true
+}
+
+// removeClient forgets a closed connection.
+func (h *Hub) removeClient(client *Client) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.users[client.UserID], client)
+	if len(h.users[client.UserID]) == 0 {
+		delete(h.users, client.UserID)
+	}
+}
+
+// deliver hands an event to the local goroutine of its room, if the room is active here.
+// Events addressed to users go straight to those users' connections instead.
+func (h *Hub) deliver(event Event) {
+	if len(event.UserIDs) > 0 {
+		h.deliverToUsers(event)
+		return
+	}
+
+	h.mu.Lock()
+	r, exists := h.rooms[event.RoomID]
+	h.mu.Unlock()
+	if !exists {
+		return
+	}
+
+	if event.Kick != nil {
+		select {
+		case r.kicks <- *event.Kick:
+		case <-r.done:
+		}
+		return
+	}
+
+	select {
+	case r.broadcast <- event:
+	case <-r.done:
+	}
+}
+
+// deliverToUsers hands an event to every local connection of the users it is addressed to.
+func (h *Hub) deliverToUsers(event Event) {
+	var clients []*Client
+	h.mu.Lock()
+	for _, userID := range event.UserIDs {
+		for client := range h.users[userID] {
+			clients = append(clients, client)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		if !client.deliver(event) {
+			log.Printf("❌ WebSocket client too slow, disconnecting (User: %d)\n", client.UserID)
+			client.CloseWith(websocket.CloseTryAgainLater, "slow consumer")
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package hub
+
+import (
+	"chatingApp/models"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// waitTimeout bounds every wait for something the hub does asynchronously.
+const waitTimeout = 2 * time.Second
+
+var testConfig = Config{
+	PingInterval:   time.Minute,
+	PongWait:       2 * time.Minute,
+	WriteWait:      time.Second,
+	MaxMessageSize: 8192,
+}
+
+func newTestHub(t *testing.T) *Hub {
+	t.Helper()
+	h, err := NewHub(testConfig, NewMemoryBroadcaster(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h
+}
+
+// testPeer is the browser side of a test client's WebSocket connection.
+type testPeer struct {
+	conn *websocket.Conn
+}
+
+// newTestClient connects a client for the user to the hub over a real WebSocket. Its
+// WritePump is not started, so tests read queued frames from the send channel directly.
+func newTestClient(t *testing.T, h *Hub, userID int) (*Client, *testPeer) {
+	t.Helper()
+
+	conns := make(chan *websocket.Conn, 1)
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		conns <- conn
+	}))
+	t.Cleanup(server.Close)
+
+	peer, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { peer.Close() })
+
+	client := h.NewClient(<-conns, userID)
+	t.Cleanup(client.Close)
+	return client, &testPeer{conn: peer}
+}
+
+// receive returns the next frame queued for the client.
+func receive(t *testing.T, client *Client) string {
+	t.Helper()
+	select {
+	case frame := <-client.send:
+		return string(frame)
+	case <-time.After(waitTimeout):
+		t.Fatalf("no frame for user %d", client.UserID)
+		return ""
+	}
+}
+
+// expectNothing checks that no frame is queued for the client.
+func expectNothing(t *testing.T, client *Client) {
+	t.Helper()
+	select {
+	case frame := <-client.send:
+		t.Errorf("unexpected frame for user %d: %s", client.UserID, frame)
+	default:
+	}
+}
+
+func TestBroadcastReachesOnlyRoomClients(t *testing.T) {
+	h := newTestHub(t)
+	alice, _ := newTestClient(t, h, 1)
+	bob, _ := newTestClient(t, h, 2)
+	carol, _ := newTestClient(t, h, 3)
+
+	h.Register(10, alice)
+	h.Register(10, bob)
+	h.Register(20, carol)
+
+	h.Broadcast(10, []byte(`"room 10"`))
+	h.Broadcast(20, []byte(`"room 20"`))
+
+	if got := receive(t, alice); got != `"room 10"` {
+		t.Errorf("alice got %s", got)
+	}
+	if got := receive(t, bob); got != `"room 10"` {
+		t.Errorf("bob got %s", got)
+	}
+	// Each room's frames arrive in order, so carol's first frame shows she missed room 10's
+	if got := receive(t, carol); got != `"room 20"` {
+		t.Errorf("carol got %s", got)
+	}
+
+	if stats := h.Stats(); stats.ActiveRooms != 2 || stats.Connections != 3 {
+		t.Errorf("Stats = %+v, want 2 rooms and 3 connections", stats)
+	}
+}
+
+func TestUnregisterStopsDeliveryAndEmptyRooms(t *testing.T) {
+	h := newTestHub(t)
+	alice, _ := newTestClient(t, h, 1)
+	bob, _ := newTestClient(t, h, 2)
+
+	h.Register(10, alice)
+	h.Register(10, bob)
+	h.Unregister(10, alice)
+
+	h.Broadcast(10, []byte(`"after"`))
+	if got := receive(t, bob); got != `"after"` {
+		t.Errorf("bob got %s", got)
+	}
+	expectNothing(t, alice)
+
+	h.Unregister(10, bob)
+	if stats := h.Stats(); stats.ActiveRooms != 0 {
+		t.Errorf("ActiveRooms = %d after the last client left, want 0", stats.ActiveRooms)
+	}
+
+	// Broadcasting to a room nobody is in here is dropped, and unregistering again is harmless
+	h.Broadcast(10, []byte(`"nobody"`))
+	h.Unregister(10, bob)
+	expectNothing(t, bob)
+
+	// The room starts again for the next client
+	h.Register(10, alice)
+	h.Broadcast(10, []byte(`"again"`))
+	if got := receive(t, alice); got != `"again"` {
+		t.Errorf("alice got %s", got)
+	}
+}
+
+func TestKickClosesPlainClients(t *testing.T) {
+	h := newTestHub(t)
+	alice, alicePeer := newTestClient(t, h, 1)
+	bob, _ := newTestClient(t, h, 2)
+
+	h.Register(10, alice)
+	h.Register(10, bob)
+	h.DisconnectUser(10, 1, CloseRemovedFromRoom, "removed from room")
+
+	select {
+	case <-alice.Done():
+	case <-time.After(waitTimeout):
+		t.Fatal("kicked client was not closed")
+	}
+
+	alicePeer.conn.SetReadDeadline(time.Now().Add(waitTimeout))
+	_, _, err := alicePeer.conn.ReadMessage()
+	if !websocket.IsCloseError(err, CloseRemovedFromRoom) {
+		t.Errorf("peer read %v, want close code %d", err, CloseRemovedFromRoom)
+	}
+
+	// Other users stay connected and the kicked client no longer receives the room
+	h.Broadcast(10, []byte(`"still here"`))
+	if got := receive(t, bob); got != `"still here"` {
+		t.Errorf("bob got %s", got)
+	}
+	expectNothing(t, alice)
+	if stats := h.Stats(); stats.Connections != 1 {
+		t.Errorf("Connections = %d, want 1", stats.Connections)
+	}
+}
+
+func TestKickUnsubscribesMultiplexedClients(t *testing.T) {
+	h := newTestHub(t)
+	alice, _ := newTestClient(t, h, 1)
+	alice.Multiplexed = true
+
+	h.Register(10, alice)
+	h.Register(20, alice)
+	h.DisconnectUser(10, 1, CloseRemovedFromRoom, "removed from room")
+
+	var envelope models.Envelope
+	if err := json.Unmarshal([]byte(receive(t, alice)), &envelope); err != nil {
+		t.Fatal(err)
+	}
+	if envelope.Type != models.EventUnsubscribed || envelope.RoomID != 10 {
+		t.Errorf("got %s for room %d, want %s for room 10", envelope.Type, envelope.RoomID, models.EventUnsubscribed)
+	}
+
+	select {
+	case <-alice.Done():
+		t.Fatal("multiplexed client was closed by a kick")
+	default:
+	}
+	if revoked := alice.TakeRevoked(); len(revoked) != 1 || revoked[0] != 10 {
+		t.Errorf("TakeRevoked = %v, want [10]", revoked)
+	}
+	if revoked := alice.TakeRevoked(); len(revoked) != 0 {
+		t.Errorf("second TakeRevoked = %v, want nothing", revoked)
+	}
+
+	// The other subscription is untouched
+	h.Broadcast(10, []byte(`"room 10"`))
+	h.Broadcast(20, []byte(`"room 20"`))
+	if got := receive(t, alice); got != `"room 20"` {
+		t.Errorf("alice got %s", got)
+	}
+}
+
+func TestSlowClientIsDisconnected(t *testing.T) {
+	h := newTestHub(t)
+	slow, _ := newTestClient(t, h, 1)
+	h.Register(10, slow)
+
+	for i := 0; i <= sendBufferSize; i++ {
+		h.Broadcast(10, []byte(fmt.Sprintf("%d", i)))
+	}
+
+	select {
+	case <-slow.Done():
+	case <-time.After(waitTimeout):
+		t.Fatal("client with a full buffer was not disconnected")
+	}
+}
+
+func TestSendToUsersReachesEveryConnection(t *testing.T) {
+	h := newTestHub(t)
+	phone, _ := newTestClient(t, h, 1)
+	laptop, _ := newTestClient(t, h, 1)
+	bob, _ := newTestClient(t, h, 2)
+
+	if err := h.SendToUsers([]int{1}, 10, models.EventReadReceipt, map[string]int{"seq": 5}); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, phone)
+	receive(t, laptop)
+	expectNothing(t, bob)
+
+	laptop.Close()
+	if err := h.SendToUsers([]int{1}, 10, models.EventReadReceipt, map[string]int{"seq": 6}); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, phone)
+	expectNothing(t, laptop)
+}
+
+// TestConcurrentRegisterBroadcast exercises the hub from many goroutines; run with -race.
+func TestConcurrentRegisterBroadcast(t *testing.T) {
+	h := newTestHub(t)
+	const users = 8
+
+	clients := make([]*Client, users)
+	for i := range clients {
+		clients[i], _ = newTestClient(t, h, i+1)
+	}
+
+	var wg sync.WaitGroup
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *Client) {
+			defer wg.Done()
+			for round := 0; round < 20; round++ {
+				roomID := 100 + (i+round)%3
+				h.Register(roomID, client)
+				h.Broadcast(roomID, []byte(`"hello"`))
+				// Drain so the client never counts as slow
+				for len(client.send) > 0 {
+					<-client.send
+				}
+				if round%5 == 0 {
+					h.DisconnectUser(roomID, client.UserID+users, CloseRemovedFromRoom, "nobody")
+				}
+				h.Unregister(roomID, client)
+				_ = h.Stats()
+			}
+		}(i, client)
+	}
+	wg.Wait()
+
+	if stats := h.Stats(); stats.ActiveRooms != 0 || stats.Connections != users {
+		t.Errorf("Stats = %+v, want no rooms and %d connections", stats, users)
+	}
+}
@@ -0,0 +1,167 @@
+package hub
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// notifyChannel is the Postgres channel hub events are published on.
+	notifyChannel = "chat_hub_events"
+	// maxNotifyPayload is the largest payload Postgres accepts in NOTIFY.
+	maxNotifyPayload = 7999
+	// storedEventRetention is how long events too large for NOTIFY are kept for listeners to load.
+	storedEventRetention = 5 * time.Minute
+)
+
+// notification is the NOTIFY payload exchanged between instances. It carries the event
+// itself, or for events too large for NOTIFY the ID of the hub_events row holding it.
+type notification struct {
+	Node  string `json:"node"` // Publishing instance, so it can skip its own events
+	Event *Event `json:"event,omitempty"`
+	Ref   int64  `json:"ref,omitempty"`
+}
+
+// PostgresBroadcaster fans events out to every server instance with Postgres LISTEN/NOTIFY.
+// Events are delivered locally at once and sent to other instances through the database.
+// Events that do not fit in a NOTIFY payload, such as long messages with attachments and
+// mentions, are stored in the hub_events table and only their ID is sent.
+type PostgresBroadcaster struct {
+	db       *sql.DB
+	listener *pq.Listener
+	node     string
+	deliver  func(Event)
+}
+
+// NewPostgresBroadcaster creates a Broadcaster that publishes through db and
+// listens on a dedicated connection opened with connStr.
+func NewPostgresBroadcaster(db *sql.DB, connStr string) *PostgresBroadcaster {
+	node := make([]byte, 8)
+	if _, err := rand.Read(node); err != nil {
+		log.Fatal("❌ Error: Failed to generate broadcaster node ID:", err)
+	}
+
+	listener := pq.NewListener(connStr, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Println("❌ Error: Broadcaster listener connection problem:", err)
+		}
+	})
+
+	return &PostgresBroadcaster{db: db, listener: listener, node: hex.EncodeToString(node)}
+}
+
+// Subscribe starts listening for events published by other instances.
+func (b *PostgresBroadcaster) Subscribe(deliver func(Event)) error {
+	b.deliver = deliver
+	if err := b.listener.Listen(notifyChannel); err != nil {
+		return err
+	}
+
+	go b.listen()
+	log.Println("✅ Success: Listening for hub events on channel", notifyChannel)
+	return nil
+}
+
+// listen delivers notifications from other instances until the listener is closed.
+func (b *PostgresBroadcaster) listen() {
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// The connection was re-established; anything sent meanwhile is gone
+				log.Println("⚠️ Warning: Broadcaster listener reconnected, events may have been missed")
+				continue
+			}
+
+			var note notification
+			if err := json.Unmarshal([]byte(n.Extra), &note); err != nil {
+				log.Println("❌ Error: Failed to decode hub event:", err)
+				continue
+			}
+			if note.Node == b.node {
+				continue
+			}
+			if note.Event == nil {
+				event, err := b.loadEvent(note.Ref)
+				if err != nil {
+					log.Println("❌ Error: Failed to load hub event:", note.Ref, err)
+					continue
+				}
+				note.Event = event
+			}
+			b.deliver(*note.Event)
+
+		case <-ping.C:
+			go b.listener.Ping()
+		}
+	}
+}
+
+// Publish delivers the event locally and notifies the other instances.
+func (b *PostgresBroadcaster) Publish(event Event) error {
+	b.deliver(event)
+
+	payload, err := json.Marshal(notification{Node: b.node, Event: &event})
+	if err != nil {
+		return err
+	}
+	if len(payload) > maxNotifyPayload {
+		if payload, err = b.storeEvent(event); err != nil {
+			return err
+		}
+	}
+
+	_, err = b.db.Exec(`SELECT pg_notify($1, $2);`, notifyChannel, string(payload))
+	return err
+}
+
+// storeEvent saves an event too large for NOTIFY and returns the notification referring to it.
+// Stored events older than storedEventRetention have been loaded by then and are removed.
+func (b *PostgresBroadcaster) storeEvent(event Event) ([]byte, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	var ref int64
+	if err := b.db.QueryRow(`INSERT INTO hub_events (event) VALUES ($1) RETURNING id;`, string(data)).Scan(&ref); err != nil {
+		return nil, err
+	}
+
+	query := `DELETE FROM hub_events WHERE created_at < CURRENT_TIMESTAMP - make_interval(secs => $1);`
+	if _, err := b.db.Exec(query, storedEventRetention.Seconds()); err != nil {
+		log.Println("❌ Error: Failed to remove old hub events:", err)
+	}
+	return json.Marshal(notification{Node: b.node, Ref: ref})
+}
+
+// loadEvent reads an event stored by storeEvent on another instance.
+func (b *PostgresBroadcaster) loadEvent(ref int64) (*Event, error) {
+	var data []byte
+	if err := b.db.QueryRow(`SELECT event FROM hub_events WHERE id = $1;`, ref).Scan(&data); err != nil {
+		return nil, err
+	}
+
+	event := &Event{}
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
+
+// Close stops listening for events.
+func (b *PostgresBroadcaster) Close() error {
+	return b.listener.Close()
+}
@@ -0,0 +1,75 @@
+package hub
+
+import (
+	"chatingApp/dbtest"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// subscribe starts a broadcaster and returns the events it delivers.
+func subscribe(t *testing.T, b *PostgresBroadcaster) <-chan Event {
+	t.Helper()
+	events := make(chan Event, 16)
+	if err := b.Subscribe(func(event Event) { events <- event }); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event delivered")
+		return Event{}
+	}
+}
+
+func TestPostgresBroadcasterFansOut(t *testing.T) {
+	db, connStr := dbtest.Open(t)
+	local := NewPostgresBroadcaster(db, connStr)
+	remote := NewPostgresBroadcaster(db, connStr)
+	localEvents := subscribe(t, local)
+	remoteEvents := subscribe(t, remote)
+
+	// Larger than a NOTIFY payload once encoded, as a long message with attachments can be
+	large, _ := json.Marshal(map[string]string{"content": strings.Repeat("é", 6000)})
+	events := []Event{
+		{RoomID: 1, Seq: 7, Frame: []byte(`{"type":"message.new"}`)},
+		{RoomID: 1, Seq: 8, Frame: large},
+		{RoomID: 1, Kick: &Kick{UserID: 3, Code: CloseRemovedFromRoom, Reason: "removed"}},
+	}
+
+	for _, event := range events {
+		if err := local.Publish(event); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+		for name, delivered := range map[string]<-chan Event{"local": localEvents, "remote": remoteEvents} {
+			got := nextEvent(t, delivered)
+			if got.RoomID != event.RoomID || got.Seq != event.Seq || string(got.Frame) != string(event.Frame) {
+				t.Errorf("%s instance got room %d seq %d (%d bytes), want room %d seq %d (%d bytes)",
+					name, got.RoomID, got.Seq, len(got.Frame), event.RoomID, event.Seq, len(event.Frame))
+			}
+			if (got.Kick == nil) != (event.Kick == nil) || (got.Kick != nil && *got.Kick != *event.Kick) {
+				t.Errorf("%s instance got kick %+v, want %+v", name, got.Kick, event.Kick)
+			}
+		}
+	}
+
+	// Instances skip their own notifications, so nothing arrives twice
+	select {
+	case event := <-localEvents:
+		t.Errorf("local instance got its own event again: %+v", event)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	var stored int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM hub_events;`).Scan(&stored); err != nil || stored != 1 {
+		t.Errorf("%d events stored (%v), want only the large one", stored, err)
+	}
+}
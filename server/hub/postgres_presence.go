@@ -0,0 +1,135 @@
+package hub
+
+import (
+	"chatingApp/models"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// presenceHeartbeat is how often an instance tells the others it is still alive.
+	presenceHeartbeat = 15 * time.Second
+	// presenceNodeTTL is how long the presence an instance reported counts without a heartbeat.
+	presenceNodeTTL = 4 * presenceHeartbeat
+	// presenceNodeRetention is how long a silent instance's rows are kept before they are removed.
+	presenceNodeRetention = time.Hour
+)
+
+// PostgresPresenceStore shares presence between server instances through the presence_nodes and
+// presence_connections tables. An instance that stops sending heartbeats, for instance because it
+// crashed, stops counting after presenceNodeTTL; its users go offline without a presence event.
+type PostgresPresenceStore struct {
+	db   *sql.DB
+	node string
+
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewPostgresPresenceStore registers this instance and starts its heartbeat.
+func NewPostgresPresenceStore(db *sql.DB) (*PostgresPresenceStore, error) {
+	node := make([]byte, 8)
+	if _, err := rand.Read(node); err != nil {
+		return nil, err
+	}
+
+	s := &PostgresPresenceStore{
+		db:   db,
+		node: hex.EncodeToString(node),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	if err := s.heartbeat(); err != nil {
+		return nil, err
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Report records the status of a user's connections to this instance; offline clears it.
+func (s *PostgresPresenceStore) Report(userID int, status string) error {
+	if status == models.PresenceOffline {
+		_, err := s.db.Exec(`DELETE FROM presence_connections WHERE node = $1 AND user_id = $2;`, s.node, userID)
+		return err
+	}
+
+	query := `INSERT INTO presence_connections (node, user_id, status) VALUES ($1, $2, $3)
+			  ON CONFLICT (node, user_id) DO UPDATE SET status = EXCLUDED.status;`
+	_, err := s.db.Exec(query, s.node, userID, status)
+	return err
+}
+
+// Statuses returns the statuses the other live instances report for each of the users.
+func (s *PostgresPresenceStore) Statuses(userIDs []int) (map[int][]string, error) {
+	query := `SELECT pc.user_id, pc.status
+			  FROM presence_connections pc JOIN presence_nodes pn ON pn.node = pc.node
+			  WHERE pc.user_id = ANY($1) AND pc.node <> $2
+			  AND pn.seen_at > CURRENT_TIMESTAMP - make_interval(secs => $3);`
+	rows, err := s.db.Query(query, pq.Array(userIDs), s.node, presenceNodeTTL.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	statuses := make(map[int][]string)
+	for rows.Next() {
+		var userID int
+		var status string
+		if err := rows.Scan(&userID, &status); err != nil {
+			return nil, err
+		}
+		statuses[userID] = append(statuses[userID], status)
+	}
+
+	return statuses, rows.Err()
+}
+
+// Close stops the heartbeat and withdraws everything this instance reported.
+func (s *PostgresPresenceStore) Close() error {
+	s.stopOnce.Do(func() { close(s.stop) })
+	<-s.done
+
+	_, err := s.db.Exec(`DELETE FROM presence_nodes WHERE node = $1;`, s.node)
+	return err
+}
+
+// run sends heartbeats and removes long-silent instances until the store is closed.
+func (s *PostgresPresenceStore) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(presenceHeartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := s.heartbeat(); err != nil {
+				log.Println("❌ Error: Failed to send presence heartbeat:", err)
+			}
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// heartbeat marks this instance as alive and removes instances silent for presenceNodeRetention,
+// together with the presence they reported.
+func (s *PostgresPresenceStore) heartbeat() error {
+	query := `INSERT INTO presence_nodes (node, seen_at) VALUES ($1, CURRENT_TIMESTAMP)
+			  ON CONFLICT (node) DO UPDATE SET seen_at = EXCLUDED.seen_at;`
+	if _, err := s.db.Exec(query, s.node); err != nil {
+		return err
+	}
+
+	query = `DELETE FROM presence_nodes WHERE seen_at < CURRENT_TIMESTAMP - make_interval(secs => $1);`
+	_, err := s.db.Exec(query, presenceNodeRetention.Seconds())
+	return err
+}
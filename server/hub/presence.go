@@ -0,0 +1,134 @@
+package hub
+
+import (
+	"chatingApp/models"
+	"log"
+	"sync"
+)
+
+// PresenceStore shares presence between server instances. Each instance reports the status
+// of its own connections of a user, and the user's status is the best one any live instance
+// reports.
+type PresenceStore interface {
+	// Report records the status of a user's connections to this instance; offline clears it.
+	Report(userID int, status string) error
+	// Statuses returns the statuses the other live instances report for each of the users.
+	Statuses(userIDs []int) (map[int][]string, error)
+}
+
+// Presence tracks the status of each user, counted across all of their connections:
+// online if any connection is online, away if every connection is away, offline once
+// the last one is gone. With a PresenceStore, connections to other instances count too.
+type Presence struct {
+	mu    sync.Mutex
+	conns map[int]map[*Client]string // userID -> connection -> status, on this instance
+	store PresenceStore              // nil when this is the only instance
+}
+
+func newPresence(store PresenceStore) *Presence {
+	return &Presence{conns: make(map[int]map[*Client]string), store: store}
+}
+
+// Connect records a new online connection. It returns the user's status and whether it changed.
+func (p *Presence) Connect(client *Client) (string, bool) {
+	return p.update(client, models.PresenceOnline)
+}
+
+// SetStatus records a connection's status, online or away. It returns the user's status and whether it changed.
+func (p *Presence) SetStatus(client *Client, status string) (string, bool) {
+	return p.update(client, status)
+}
+
+// Disconnect forgets a closed connection. It returns the user's status and whether it changed.
+func (p *Presence) Disconnect(client *Client) (string, bool) {
+	return p.change(client.UserID, func() {
+		delete(p.conns[client.UserID], client)
+		if len(p.conns[client.UserID]) == 0 {
+			delete(p.conns, client.UserID)
+		}
+	})
+}
+
+// Status returns a user's current status.
+func (p *Presence) Status(userID int) string {
+	return p.Statuses([]int{userID})[userID]
+}
+
+// Statuses returns the current status of each of the users.
+func (p *Presence) Statuses(userIDs []int) map[int]string {
+	remote := p.remoteStatuses(userIDs)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	statuses := make(map[int]string, len(userIDs))
+	for _, userID := range userIDs {
+		statuses[userID] = combineStatuses(append(remote[userID], p.localStatusLocked(userID)))
+	}
+	return statuses
+}
+
+func (p *Presence) update(client *Client, status string) (string, bool) {
+	return p.change(client.UserID, func() {
+		if p.conns[client.UserID] == nil {
+			p.conns[client.UserID] = make(map[*Client]string)
+		}
+		p.conns[client.UserID][client] = status
+	})
+}
+
+// change applies a change to a user's connections and reports the new local status to the
+// store. It returns the user's status and whether it changed. The lock is held while
+// reporting, so the reports of one user reach the store in order.
+func (p *Presence) change(userID int, apply func()) (string, bool) {
+	remote := p.remoteStatuses([]int{userID})[userID]
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	local := p.localStatusLocked(userID)
+	before := combineStatuses(append(remote, local))
+	apply()
+	if changed := p.localStatusLocked(userID); p.store != nil && changed != local {
+		if err := p.store.Report(userID, changed); err != nil {
+			log.Println("❌ Error: Failed to share presence:", err)
+		}
+	}
+	after := combineStatuses(append(remote, p.localStatusLocked(userID)))
+	return after, after != before
+}
+
+// remoteStatuses returns what other instances report for the users, or nothing without a store.
+func (p *Presence) remoteStatuses(userIDs []int) map[int][]string {
+	if p.store == nil {
+		return nil
+	}
+	remote, err := p.store.Statuses(userIDs)
+	if err != nil {
+		log.Println("❌ Error: Failed to read shared presence:", err)
+		return nil
+	}
+	return remote
+}
+
+func (p *Presence) localStatusLocked(userID int) string {
+	statuses := make([]string, 0, len(p.conns[userID]))
+	for _, status := range p.conns[userID] {
+		statuses = append(statuses, status)
+	}
+	return combineStatuses(statuses)
+}
+
+// combineStatuses returns online if any status is online, away if any is away, and offline otherwise.
+func combineStatuses(statuses []string) string {
+	combined := models.PresenceOffline
+	for _, status := range statuses {
+		switch status {
+		case models.PresenceOnline:
+			return models.PresenceOnline
+		case models.PresenceAway:
+			combined = models.PresenceAway
+		}
+	}
+	return combined
+}
@@ -0,0 +1,99 @@
+package hub
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waitHeld waits until the client holds n events.
+func waitHeld(t *testing.T, client *Client, n int) {
+	t.Helper()
+	deadline := time.Now().Add(waitTimeout)
+	for {
+		client.holdMu.Lock()
+		held := len(client.held)
+		client.holdMu.Unlock()
+		if held == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d events held, want %d", held, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHoldReleaseSkipsReplayedMessages(t *testing.T) {
+	h := newTestHub(t)
+	alice, _ := newTestClient(t, h, 1)
+	alice.Multiplexed = true
+
+	// Already subscribed to another room, where numbers overlap with the resumed one
+	h.Register(20, alice)
+
+	alice.Hold()
+	h.Register(10, alice)
+	for seq := int64(1); seq <= 3; seq++ {
+		h.publish(Event{RoomID: 10, Seq: seq, Frame: []byte(fmt.Sprintf(`"10:%d"`, seq))})
+	}
+	h.publish(Event{RoomID: 20, Seq: 1, Frame: []byte(`"20:1"`)})
+	h.Broadcast(10, []byte(`"typing"`))
+
+	waitHeld(t, alice, 5)
+	expectNothing(t, alice)
+
+	// Messages up to seq 2 of room 10 went out in the replay; everything else follows, each
+	// room's events in order
+	alice.Release(10, 2)
+	var room10 []string
+	room20 := 0
+	for i := 0; i < 3; i++ {
+		if got := receive(t, alice); got == `"20:1"` {
+			room20++
+		} else {
+			room10 = append(room10, got)
+		}
+	}
+	if room20 != 1 || len(room10) != 2 || room10[0] != `"10:3"` || room10[1] != `"typing"` {
+		t.Errorf("after release got room 10 %v and %d room 20 frames, want [\"10:3\" \"typing\"] and 1", room10, room20)
+	}
+	expectNothing(t, alice)
+
+	h.Broadcast(10, []byte(`"live"`))
+	if got := receive(t, alice); got != `"live"` {
+		t.Errorf("frame after release = %s", got)
+	}
+}
+
+func TestReleaseWithNothingReplayed(t *testing.T) {
+	h := newTestHub(t)
+	alice, _ := newTestClient(t, h, 1)
+
+	alice.Hold()
+	h.Register(10, alice)
+	h.publish(Event{RoomID: 10, Seq: 1, Frame: []byte(`"1"`)})
+	waitHeld(t, alice, 1)
+
+	alice.Release(10, 0)
+	if got := receive(t, alice); got != `"1"` {
+		t.Errorf("frame after release = %s, want the held message", got)
+	}
+}
+
+func TestHoldingTooManyEventsDisconnects(t *testing.T) {
+	h := newTestHub(t)
+	alice, _ := newTestClient(t, h, 1)
+
+	alice.Hold()
+	h.Register(10, alice)
+	for seq := int64(1); seq <= maxHeldEvents+1; seq++ {
+		h.publish(Event{RoomID: 10, Seq: seq, Frame: []byte(`"m"`)})
+	}
+
+	select {
+	case <-alice.Done():
+	case <-time.After(waitTimeout):
+		t.Fatal("client holding too many events was not disconnected")
+	}
+}
@@ -0,0 +1,66 @@
+package hub
+
+import (
+	"log"
+
+	"github.com/gorilla/websocket"
+)
+
+// room is the goroutine-owned state of a single active chat room.
+type room struct {
+	id      int
+	members int // Registered clients, guarded by Hub.mu
+
+	clients    map[*Client]bool
+	register   chan *Client
+	unregister chan *Client
+	broadcast  chan Event
+	kicks      chan Kick
+	done       chan struct{}
+}
+
+func newRoom(id int) *room {
+	return &room{
+		id:         id,
+		clients:    make(map[*Client]bool),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		broadcast:  make(chan Event, roomBroadcastBuffer),
+		kicks:      make(chan Kick),
+		done:       make(chan struct{}),
+	}
+}
+
+// run owns the room's client set until the room is stopped.
+func (r *room) run() {
+	for {
+		select {
+		case client := <-r.register:
+			r.clients[client] = true
+
+		case client := <-r.unregister:
+			delete(r.clients, client)
+
+		case event := <-r.broadcast:
+			for client := range r.clients {
+				// Never wait on a client: one whose buffer is full is disconnected
+				if !client.deliver(event) {
+					log.Printf("❌ WebSocket client too slow, disconnecting (Room: %d, User: %d)\n", r.id, client.UserID)
+					delete(r.clients, client)
+					client.CloseWith(websocket.CloseTryAgainLater, "slow consumer")
+				}
+			}
+
+		case k := <-r.kicks:
+			for client := range r.clients {
+				if client.UserID == k.UserID {
+					delete(r.clients, client)
+					client.revoke(r.id, k)
+				}
+			}
+
+		case <-r.done:
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package hub
+
+import (
+	"chatingApp/models"
+	"sync"
+	"time"
+)
+
+const (
+	// typingTTL is how long a typing indicator lasts without being refreshed.
+	typingTTL = 5 * time.Second
+	// typingThrottle is the shortest interval between two typing broadcasts of one user in one room.
+	typingThrottle = 2 * time.Second
+)
+
+type typingKey struct {
+	roomID int
+	userID int
+}
+
+type typingState struct {
+	lastSent time.Time
+	timer    *time.Timer
+}
+
+// Typing tracks who is typing in which room. Broadcasts are throttled per user and
+// room, and an indicator that is not refreshed expires on its own.
+type Typing struct {
+	hub    *Hub
+	mu     sync.Mutex
+	active map[typingKey]*typingState
+}
+
+func newTyping(h *Hub) *Typing {
+	return &Typing{hub: h, active: make(map[typingKey]*typingState)}
+}
+
+// Start marks a user as typing in a room, or refreshes the indicator if they already are.
+func (t *Typing) Start(roomID, userID int) {
+	key := typingKey{roomID: roomID, userID: userID}
+
+	t.mu.Lock()
+	state, exists := t.active[key]
+	if exists {
+		state.timer.Reset(typingTTL)
+		if time.Since(state.lastSent) < typingThrottle {
+			t.mu.Unlock()
+			return
+		}
+	} else {
+		state = &typingState{timer: time.AfterFunc(typingTTL, func() { t.expire(key, state) })}
+		t.active[key] = state
+	}
+	state.lastSent = time.Now()
+	t.mu.Unlock()
+
+	t.hub.BroadcastEvent(roomID, models.EventTyping, models.TypingPayload{
+		UserID:      userID,
+		Typing:      true,
+		ExpiresInMs: typingTTL.Milliseconds(),
+	})
+}
+
+// Stop clears a user's typing indicator in a room, if it is set.
+func (t *Typing) Stop(roomID, userID int) {
+	key := typingKey{roomID: roomID, userID: userID}
+
+	t.mu.Lock()
+	state, exists := t.active[key]
+	if exists {
+		state.timer.Stop()
+		delete(t.active, key)
+	}
+	t.mu.Unlock()
+
+	if exists {
+		t.hub.BroadcastEvent(roomID, models.EventTyping, models.TypingPayload{UserID: userID, Typing: false})
+	}
+}
+
+// expire clears an indicator whose time ran out, unless it was replaced meanwhile.
+func (t *Typing) expire(key typingKey, state *typingState) {
+	t.mu.Lock()
+	current, exists := t.active[key]
+	if !exists || current != state {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.active, key)
+	t.mu.Unlock()
+
+	t.hub.BroadcastEvent(key.roomID, models.EventTyping, models.TypingPayload{UserID: key.userID, Typing: false})
+}
@@ -1,12 +1,15 @@
 package main
 
 import (
+	"chatingApp/config"
 	"chatingApp/db"
 	"chatingApp/handlers"
+	"chatingApp/hub"
 	"chatingApp/middleware"
 	"chatingApp/repository"
 	"chatingApp/routes"
 	"chatingApp/services"
+	"chatingApp/storage"
 	"github.com/gin-gonic/gin"
 	"log"
 )
@@ -19,17 +22,100 @@ func main() {
 	userRepo := repository.NewUserRepository(db.DB)
 	systemLogRepo := repository.NewSystemLogRepository(db.DB)
 	roomRepo := repository.NewRoomRepository(db.DB)
+	searchRepo := repository.NewSearchRepository(db.DB)
+	attachmentRepo := repository.NewAttachmentRepository(db.DB)
+	scheduledRepo := repository.NewScheduledMessageRepository(db.DB)
+	retentionRepo := repository.NewRetentionRepository(db.DB)
+	exportRepo := repository.NewExportRepository(db.DB)
+	inviteRepo := repository.NewInviteRepository(db.DB)
+	joinRequestRepo := repository.NewJoinRequestRepository(db.DB)
+	conversationRepo := repository.NewConversationRepository(db.DB)
+
+	// Initialize realtime hub (one goroutine per active room)
+	var broadcaster hub.Broadcaster = hub.NewMemoryBroadcaster()
+	var presenceStore hub.PresenceStore
+	if config.AppConfig.Broadcaster == "postgres" {
+		// Fan room events out to every server instance through LISTEN/NOTIFY
+		broadcaster = hub.NewPostgresBroadcaster(db.DB, db.ConnectionString())
+
+		// Count every instance's connections in each user's presence
+		postgresPresence, err := hub.NewPostgresPresenceStore(db.DB)
+		if err != nil {
+			log.Fatal("❌ Error: Failed to start shared presence:", err)
+		}
+		defer postgresPresence.Close()
+		presenceStore = postgresPresence
+	}
+	defer broadcaster.Close()
+
+	chatHub, err := hub.NewHub(hub.Config{
+		PingInterval:   config.AppConfig.WSPingInterval,
+		PongWait:       config.AppConfig.WSPongWait,
+		WriteWait:      config.AppConfig.WSWriteWait,
+		MaxMessageSize: config.AppConfig.WSMaxMessageSize,
+	}, broadcaster, presenceStore)
+	if err != nil {
+		log.Fatal("❌ Error: Failed to start realtime hub:", err)
+	}
+
+	// Initialize file storage for attachments
+	var fileStorage storage.Storage
+	if config.AppConfig.StorageBackend == "s3" {
+		fileStorage, err = storage.NewS3Storage(storage.S3Config{
+			Endpoint:  config.AppConfig.S3Endpoint,
+			Region:    config.AppConfig.S3Region,
+			Bucket:    config.AppConfig.S3Bucket,
+			AccessKey: config.AppConfig.S3AccessKey,
+			SecretKey: config.AppConfig.S3SecretKey,
+			PathStyle: config.AppConfig.S3PathStyle,
+			Timeout:   config.AppConfig.S3Timeout,
+		})
+	} else {
+		fileStorage, err = storage.NewLocalStorage(config.AppConfig.StorageLocalDir)
+	}
+	if err != nil {
+		log.Fatal("❌ Error: Failed to initialize file storage:", err)
+	}
 
 	// Initialize services
 	userService := services.NewUserService(userRepo)
 	systemLogService := services.NewSystemLogService(systemLogRepo)
-	roomService := services.NewRoomService(roomRepo)
+	permissionService := services.NewRoomPermissionService(roomRepo)
+	roomService := services.NewRoomService(roomRepo, attachmentRepo, permissionService, chatHub)
+	searchService := services.NewSearchService(searchRepo, permissionService)
+	attachmentService := services.NewAttachmentService(attachmentRepo, roomRepo, permissionService, fileStorage)
+	scheduledService := services.NewScheduledMessageService(scheduledRepo, roomService)
+	inviteService := services.NewInviteService(inviteRepo, roomRepo, permissionService)
+	directoryService := services.NewRoomDirectoryService(roomRepo, joinRequestRepo, permissionService)
+	conversationService := services.NewConversationService(conversationRepo)
+
+	// Post scheduled messages as they come due
+	scheduledService.StartDispatcher(config.AppConfig.ScheduledDispatchInterval)
+	defer scheduledService.StopDispatcher()
+
+	// Remove messages and system logs past their retention period
+	retentionService := services.NewRetentionService(retentionRepo, permissionService, fileStorage)
+	retentionService.StartPurger(config.AppConfig.PurgeInterval)
+	defer retentionService.StopPurger()
+
+	// Build room exports queued to run in the background
+	exportService := services.NewExportService(exportRepo, roomRepo, permissionService, fileStorage)
+	exportService.StartWorker()
+	defer exportService.StopWorker()
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
 	systemLogHandler := handlers.NewSystemLogHandler(systemLogService)
 	roomHandler := handlers.NewRoomHandler(roomService)
-	wsHandler := handlers.NewWebSocketHandler(roomService) // WebSocket handler
+	wsHandler := handlers.NewWebSocketHandler(roomService, chatHub) // WebSocket handler
+	searchHandler := handlers.NewSearchHandler(searchService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	scheduledHandler := handlers.NewScheduledMessageHandler(scheduledService)
+	retentionHandler := handlers.NewRetentionHandler(retentionService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	inviteHandler := handlers.NewInviteHandler(inviteService)
+	directoryHandler := handlers.NewRoomDirectoryHandler(directoryService)
+	conversationHandler := handlers.NewConversationHandler(conversationService)
 
 	// Initialize router
 	router := gin.Default()
@@ -37,7 +123,7 @@ func main() {
 	router.Use(middleware.SystemLogMiddleware()) // Middleware to log all requests
 
 	// Setup routes (moved to app_routes.go)
-	routes.SetupRoutes(router, userHandler, systemLogHandler, roomHandler, wsHandler)
+	routes.SetupRoutes(router, userHandler, systemLogHandler, roomHandler, wsHandler, searchHandler, attachmentHandler, scheduledHandler, retentionHandler, exportHandler, inviteHandler, directoryHandler, conversationHandler)
 
 	log.Println("🚀 Server started on port 8080")
 	router.Run(":8080")
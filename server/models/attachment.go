@@ -0,0 +1,32 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Attachment represents a file uploaded to a room, sent with at most one message.
+type Attachment struct {
+	ID           int       `json:"id"`
+	RoomID       int       `json:"room_id"`
+	MessageID    *int      `json:"message_id,omitempty"` // nil until the upload is sent with a message
+	UploaderID   int       `json:"uploader_id"`          // 0 if the uploader was since removed
+	Filename     string    `json:"filename"`
+	ContentType  string    `json:"content_type"`
+	Size         int64     `json:"size"`             // In bytes
+	Width        int       `json:"width,omitempty"`  // Images only
+	Height       int       `json:"height,omitempty"` // Images only
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnail_url,omitempty"`
+	StorageKey   string    `json:"-"`
+	ThumbnailKey string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// SetURLs fills in the download URLs of the attachment.
+func (a *Attachment) SetURLs() {
+	a.URL = fmt.Sprintf("/attachments/%d", a.ID)
+	if a.ThumbnailKey != "" {
+		a.ThumbnailURL = fmt.Sprintf("/attachments/%d/thumbnail", a.ID)
+	}
+}
@@ -0,0 +1,14 @@
+package models
+
+// Conversation represents a direct or group conversation in the caller's conversation list.
+// Its ID is the ID of the room behind it, so messages go through the room endpoints and socket.
+type Conversation struct {
+	RoomSummary
+	Participants []UserResponse `json:"participants"`
+}
+
+// ConversationCreateRequest represents the payload for opening a conversation with other users.
+// The caller is always a participant and need not be listed.
+type ConversationCreateRequest struct {
+	UserIDs []int `json:"user_ids" binding:"required,min=1"`
+}
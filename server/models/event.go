@@ -0,0 +1,150 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ProtocolVersion is the current version of the WebSocket event envelope.
+const ProtocolVersion = 1
+
+// Event types exchanged over the WebSocket.
+const (
+	EventMessageSend = "message.send" // Client -> server: post a message
+	EventMessageAck  = "message.ack"  // Server -> sender: message stored
+	EventMessageNew  = "message.new"  // Server -> room: a new message was posted
+	EventError       = "error"        // Server -> client: a frame could not be handled
+
+	EventMessageEdited  = "message.edited"  // Server -> room: a message's content changed
+	EventMessageDeleted = "message.deleted" // Server -> room: a message was replaced by a tombstone
+
+	EventThreadReply   = "thread.reply"   // Server -> room: a reply was posted in a thread
+	EventThreadUpdated = "thread.updated" // Server -> thread followers: a thread they follow got a reply
+
+	EventReactionAdded   = "reaction.added"   // Server -> room: a member reacted to a message
+	EventReactionRemoved = "reaction.removed" // Server -> room: a member took back a reaction
+
+	EventMessagePinned   = "message.pinned"   // Server -> room: an admin pinned a message
+	EventMessageUnpinned = "message.unpinned" // Server -> room: a message was unpinned
+
+	EventMention = "mention" // Server -> mentioned user: a message mentioned them
+
+	EventResyncRequired = "resync.required" // Server -> client: too far behind to replay, refetch history over REST
+
+	EventSubscribe    = "subscribe"    // Client -> server: start receiving a room's events
+	EventUnsubscribe  = "unsubscribe"  // Client -> server: stop receiving a room's events
+	EventSubscribed   = "subscribed"   // Server -> client: subscription confirmed
+	EventUnsubscribed = "unsubscribed" // Server -> client: subscription ended, by request or by removal from the room
+
+	EventTyping   = "typing"   // Both ways: a user started or stopped typing in a room
+	EventPresence = "presence" // Client -> server: set own status; server -> room: a member's status changed
+
+	EventRead        = "read"         // Client -> server: mark the room read up to a message
+	EventReadReceipt = "read.receipt" // Server -> room: a member's read marker moved
+)
+
+// Presence statuses of a user, counted across all of their connections.
+const (
+	PresenceOnline  = "online"
+	PresenceAway    = "away"
+	PresenceOffline = "offline"
+)
+
+// Machine-readable codes carried by error events.
+const (
+	ErrCodeInvalidFrame       = "invalid_frame"
+	ErrCodeUnsupportedVersion = "unsupported_version"
+	ErrCodeUnknownType        = "unknown_type"
+	ErrCodeInvalidPayload     = "invalid_payload"
+	ErrCodeEmptyMessage       = "empty_message"
+	ErrCodeNotRoomMember      = "not_room_member"
+	ErrCodeMissingRoom        = "missing_room"
+	ErrCodeTooManyRooms       = "too_many_rooms"
+	ErrCodeInvalidStatus      = "invalid_status"
+	ErrCodeNotSubscribed      = "not_subscribed"
+	ErrCodeMessageNotFound    = "message_not_found"
+	ErrCodeAttachment         = "attachment_unavailable"
+	ErrCodeInternal           = "internal_error"
+)
+
+// Envelope wraps every event sent over the WebSocket in either direction.
+type Envelope struct {
+	Version int             `json:"v"`
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`      // Client-chosen ID, echoed back on the matching ack or error
+	RoomID  int             `json:"room_id,omitempty"` // Room the event belongs to
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// MessageSendPayload is the payload of a message.send event.
+type MessageSendPayload struct {
+	Content       string `json:"content"`
+	ParentID      int    `json:"parent_id,omitempty"`      // Message to reply to in a thread
+	AttachmentIDs []int  `json:"attachment_ids,omitempty"` // Files uploaded beforehand with POST /rooms/:id/attachments
+}
+
+// MessageAckPayload is the payload of a message.ack event.
+type MessageAckPayload struct {
+	MessageID int       `json:"message_id"` // Server-assigned message ID
+	Seq       int64     `json:"seq"`        // Room sequence number of the message
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ResyncRequiredPayload is the payload of a resync.required event.
+type ResyncRequiredPayload struct {
+	LastSeq int64 `json:"last_seq"` // Newest sequence number in the room
+}
+
+// SubscribePayload is the payload of a subscribe event.
+type SubscribePayload struct {
+	LastSeq *int64 `json:"last_seq,omitempty"` // Resume after this sequence number
+}
+
+// SubscribedPayload is the payload of a subscribed event.
+type SubscribedPayload struct {
+	LastSeq int64 `json:"last_seq"` // Newest sequence number in the room when the subscription started
+}
+
+// UnsubscribedPayload is the payload of an unsubscribed event.
+type UnsubscribedPayload struct {
+	Code   int    `json:"code,omitempty"` // Close code when the subscription was revoked by the server
+	Reason string `json:"reason,omitempty"`
+}
+
+// ErrorPayload is the payload of an error event.
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// TypingPayload is the payload of a typing event. Clients send only Typing;
+// the server adds the user and, while typing, how long the indicator lasts.
+type TypingPayload struct {
+	UserID      int   `json:"user_id,omitempty"`
+	Typing      bool  `json:"typing"`
+	ExpiresInMs int64 `json:"expires_in_ms,omitempty"`
+}
+
+// PresencePayload is the payload of a presence event. Clients send only Status.
+type PresencePayload struct {
+	UserID int    `json:"user_id,omitempty"`
+	Status string `json:"status"`
+}
+
+// ReadPayload is the payload of a read event.
+type ReadPayload struct {
+	MessageID int `json:"message_id"`
+}
+
+// PinPayload is the payload of message.pinned and message.unpinned events.
+type PinPayload struct {
+	MessageID int `json:"message_id"`
+	UserID    int `json:"user_id"` // Admin who pinned or unpinned the message
+}
+
+// ReactionPayload is the payload of reaction.added and reaction.removed events.
+type ReactionPayload struct {
+	MessageID int    `json:"message_id"`
+	UserID    int    `json:"user_id"`
+	Emoji     string `json:"emoji"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// Transcript formats a room can be exported in.
+const (
+	ExportJSON = "json"
+	ExportCSV  = "csv"
+	ExportHTML = "html"
+	ExportText = "txt"
+)
+
+// Statuses of a background export job.
+const (
+	ExportPending = "pending"
+	ExportRunning = "running"
+	ExportDone    = "done"
+	ExportFailed  = "failed"
+)
+
+// ExportQuery holds what to put in a room transcript.
+type ExportQuery struct {
+	RoomID int
+	Format string     // json, csv, html or txt
+	From   *time.Time // Messages sent at or after this time
+	To     *time.Time // Messages sent before this time
+}
+
+// ExportMessage represents one message in a room transcript.
+type ExportMessage struct {
+	ID          int        `json:"id"`
+	Seq         int64      `json:"seq"`
+	ParentID    *int       `json:"parent_id,omitempty"`
+	UserID      int        `json:"user_id"`
+	Author      string     `json:"author"`                // Name of the sender
+	Content     string     `json:"content"`               // Empty for deleted messages
+	Attachments []string   `json:"attachments,omitempty"` // File names
+	EditedAt    *time.Time `json:"edited_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// ExportJob represents a room export running in the background.
+type ExportJob struct {
+	ID          int        `json:"id"`
+	RoomID      int        `json:"room_id"`
+	RequestedBy int        `json:"requested_by"`
+	Format      string     `json:"format"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	Status      string     `json:"status"`
+	Size        int64      `json:"size,omitempty"`         // Bytes in the finished transcript
+	Error       string     `json:"error,omitempty"`        // Why the job failed
+	DownloadURL string     `json:"download_url,omitempty"` // Set once the job is done
+	StorageKey  string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Invite statuses, worked out from an invite's revocation, expiry and uses.
+const (
+	InviteActive  = "active"
+	InviteRevoked = "revoked"
+	InviteExpired = "expired"
+	InviteUsedUp  = "used_up"
+)
+
+// RoomInvite represents a code that lets users join a room.
+type RoomInvite struct {
+	ID           int        `json:"id"`
+	RoomID       int        `json:"room_id"`
+	Code         string     `json:"code"`
+	URL          string     `json:"url"`                      // Preview the invite here; POST to URL/accept to join
+	CreatedBy    int        `json:"created_by"`               // 0 if the creator was since removed
+	TargetUserID *int       `json:"target_user_id,omitempty"` // Only this user may accept, if set
+	MaxUses      *int       `json:"max_uses,omitempty"`       // nil for unlimited
+	Uses         int        `json:"uses"`
+	Status       string     `json:"status"`
+	ExpiresAt    time.Time  `json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// SetStatus fills in the URL of the invite and its status as of now.
+func (i *RoomInvite) SetStatus(now time.Time) {
+	i.URL = fmt.Sprintf("/invites/%s", i.Code)
+	switch {
+	case i.RevokedAt != nil:
+		i.Status = InviteRevoked
+	case !now.Before(i.ExpiresAt):
+		i.Status = InviteExpired
+	case i.MaxUses != nil && i.Uses >= *i.MaxUses:
+		i.Status = InviteUsedUp
+	default:
+		i.Status = InviteActive
+	}
+}
+
+// InviteCreateRequest represents the payload for creating a room invite.
+type InviteCreateRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Defaults to INVITE_DEFAULT_TTL from now
+	MaxUses   *int       `json:"max_uses,omitempty" binding:"omitempty,min=1"`
+	UserID    *int       `json:"user_id,omitempty"` // Restrict the invite to this user
+}
+
+// InvitePreview represents what a user sees of an invite before accepting it.
+type InvitePreview struct {
+	Code            string    `json:"code"`
+	RoomID          int       `json:"room_id"`
+	RoomName        string    `json:"room_name"`
+	RoomDescription string    `json:"room_description,omitempty"`
+	Member          bool      `json:"member"` // Whether the caller is already in the room
+	ExpiresAt       time.Time `json:"expires_at"`
+}
+
+// InviteUse represents a user who joined a room through an invite.
+type InviteUse struct {
+	InviteID int       `json:"invite_id"`
+	UserID   int       `json:"user_id"`
+	UserName string    `json:"user_name"`
+	JoinedAt time.Time `json:"joined_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// Join request statuses. Only pending requests can be approved, denied or cancelled.
+const (
+	JoinRequestPending   = "pending"
+	JoinRequestApproved  = "approved"
+	JoinRequestDenied    = "denied"
+	JoinRequestCancelled = "cancelled" // Withdrawn by the user who asked
+)
+
+// JoinRequest represents a user asking to join a room whose visibility is request.
+type JoinRequest struct {
+	ID        int        `json:"id"`
+	RoomID    int        `json:"room_id"`
+	UserID    int        `json:"user_id"`
+	UserName  string     `json:"user_name"`
+	Message   string     `json:"message,omitempty"` // Optional note to the moderators
+	Status    string     `json:"status"`            // pending, approved, denied or cancelled
+	DecidedBy *int       `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// JoinRoomRequest represents the payload for joining a room from the directory.
+type JoinRoomRequest struct {
+	Message string `json:"message,omitempty"` // Kept with the join request of a request room
+}
+
+// JoinRoomResponse represents the outcome of joining a room: either the user is now a
+// member, or their join request awaits a moderator.
+type JoinRoomResponse struct {
+	Joined      bool         `json:"joined"`
+	Room        *Room        `json:"room,omitempty"`
+	JoinRequest *JoinRequest `json:"join_request,omitempty"`
+}
+
+// RoomDirectoryQuery holds the filters of a room directory listing.
+type RoomDirectoryQuery struct {
+	Query  string // Matched against room names and descriptions
+	Limit  int
+	Offset int
+}
+
+// RoomDirectoryEntry represents a room as listed in the room directory.
+type RoomDirectoryEntry struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Visibility  string    `json:"visibility"`
+	MemberCount int       `json:"member_count"`
+	Member      bool      `json:"member"`    // Whether the caller is in the room
+	Requested   bool      `json:"requested"` // Whether the caller has a pending join request
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// RoomDirectoryResponse represents a page of the room directory returned in API responses.
+type RoomDirectoryResponse struct {
+	Rooms   []RoomDirectoryEntry `json:"rooms"`
+	HasMore bool                 `json:"has_more"` // More rooms exist beyond this page
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// What happens to messages past their room's retention period.
+const (
+	RetentionDelete  = "delete"  // Removed for good
+	RetentionArchive = "archive" // Moved to archived_messages
+)
+
+// RetentionPolicy represents how long a room keeps its messages.
+type RetentionPolicy struct {
+	RoomID        int    `json:"room_id"`
+	RetentionDays int    `json:"retention_days"` // 0 keeps messages forever
+	Action        string `json:"action"`         // delete or archive
+	Inherited     bool   `json:"inherited"`      // Whether the server-wide default applies
+}
+
+// RetentionUpdateRequest represents the payload for setting a room's retention policy.
+// A missing or null field falls back to the server-wide default.
+type RetentionUpdateRequest struct {
+	RetentionDays *int    `json:"retention_days"`
+	Action        *string `json:"action"`
+}
+
+// PurgeRun represents one pass of the background purger.
+type PurgeRun struct {
+	ID                int       `json:"id"`
+	StartedAt         time.Time `json:"started_at"`
+	FinishedAt        time.Time `json:"finished_at"`
+	MessagesDeleted   int       `json:"messages_deleted"`
+	MessagesArchived  int       `json:"messages_archived"`
+	SystemLogsDeleted int       `json:"system_logs_deleted"`
+	ExportsExpired    int       `json:"exports_expired"`
+	UploadsExpired    int       `json:"uploads_expired"` // Attachments never sent with a message
+	Error             string    `json:"error,omitempty"` // First error that cut the run short
+}
@@ -8,33 +8,116 @@ type Room struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description,omitempty"` // Optional room description
 	CreatedBy   int       `json:"created_by"`            // User ID of the creator
-	RoomAdmins  []int     `json:"room_admins"`           // List of admins
+	OwnerID     int       `json:"owner_id"`              // Current owner, 0 if the owner's account was removed
+	Visibility  string    `json:"visibility"`            // public, private or request
+	Kind        string    `json:"kind"`                  // room, direct or group
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// Room roles, from most to least privileged. A room has exactly one owner.
+const (
+	RoomRoleOwner     = "owner"
+	RoomRoleModerator = "moderator"
+	RoomRoleMember    = "member"
+)
+
+// Room visibilities: who can find a room in the directory and how they get in.
+const (
+	RoomPublic  = "public"  // Listed, and anyone can join
+	RoomPrivate = "private" // Unlisted, invite only
+	RoomRequest = "request" // Listed, joining needs a moderator's approval
+)
+
+// Room kinds. Direct and group conversations are rooms between users, without an owner, that
+// are never listed in the directory.
+const (
+	RoomKindRoom   = "room"
+	RoomKindDirect = "direct" // Two users
+	RoomKindGroup  = "group"  // Three or more users
+)
+
+// RoomMember represents a user who is a member of a room.
+type RoomMember struct {
+	UserResponse
+	RoomRole string    `json:"room_role"` // owner, moderator or member
+	JoinedAt time.Time `json:"joined_at"`
+}
+
 // Message represents a message sent in a chat room.
 type Message struct {
+	ID          int             `json:"id"`
+	RoomID      int             `json:"room_id"`             // Associated room ID
+	UserID      int             `json:"user_id"`             // ID of the sender
+	Seq         int64           `json:"seq"`                 // Per-room sequence number, increasing by one per message
+	ParentID    *int            `json:"parent_id,omitempty"` // Top-level message of the thread this reply belongs to
+	ReplyCount  int             `json:"reply_count"`         // Replies in the thread this message started
+	Content     string          `json:"content"`             // Empty once the message is deleted
+	EditedAt    *time.Time      `json:"edited_at,omitempty"`
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty"` // Set on tombstones of deleted messages
+	DeletedBy   *int            `json:"deleted_by,omitempty"` // Author or moderator who deleted the message
+	Reactions   []ReactionCount `json:"reactions,omitempty"`  // Filled in when messages are listed
+	Attachments []Attachment    `json:"attachments,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// EventType is the event a stored message is announced to its room with.
+func (m *Message) EventType() string {
+	if m.ParentID != nil {
+		return EventThreadReply
+	}
+	return EventMessageNew
+}
+
+// ReactionCount represents how many users reacted to a message with one emoji.
+type ReactionCount struct {
+	Emoji   string `json:"emoji"`
+	Count   int    `json:"count"`
+	Reacted bool   `json:"reacted"` // Whether the requesting user is one of them
+}
+
+// PinnedMessage represents a message pinned to the top of a room by a moderator or its owner.
+type PinnedMessage struct {
+	Message  Message   `json:"message"`
+	PinnedBy int       `json:"pinned_by"` // Admin who pinned it, 0 if since removed
+	PinnedAt time.Time `json:"pinned_at"`
+}
+
+// MessageEdit represents a previous version of a message, kept when it is edited or deleted.
+type MessageEdit struct {
 	ID        int       `json:"id"`
-	RoomID    int       `json:"room_id"` // Associated room ID
-	UserID    int       `json:"user_id"` // ID of the sender
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
+	MessageID int       `json:"message_id"`
+	Content   string    `json:"content"`   // Content before the change
+	EditedBy  int       `json:"edited_by"` // User who made the change, 0 if since removed
+	EditedAt  time.Time `json:"edited_at"`
 }
 
 // RoomCreateRequest represents the payload for creating a new room.
 type RoomCreateRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Description string `json:"description,omitempty"`
+	Visibility  string `json:"visibility,omitempty" binding:"omitempty,oneof=public private request"` // Defaults to private
 }
 
 // RoomUpdateRequest represents the payload for updating room details.
 type RoomUpdateRequest struct {
 	Name        *string `json:"name,omitempty"`
 	Description *string `json:"description,omitempty"`
+	Visibility  *string `json:"visibility,omitempty" binding:"omitempty,oneof=public private request"`
 	Users       *[]int  `json:"users,omitempty"`
 }
 
+// RoomRoleRequest represents the payload for changing a member's room role.
+// Ownership changes hands through a RoomTransferRequest instead.
+type RoomRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=moderator member"`
+}
+
+// RoomTransferRequest represents the payload for handing room ownership to another member.
+type RoomTransferRequest struct {
+	UserID int `json:"user_id" binding:"required"`
+}
+
 // RoomResponse represents the room object returned in API responses.
 type RoomResponse struct {
 	ID          int       `json:"id"`
@@ -43,10 +126,56 @@ type RoomResponse struct {
 	CreatedBy   int       `json:"created_by"`
 	Users       []int     `json:"users"`
 	Messages    []Message `json:"messages"`
+	HasMore     bool      `json:"has_more"` // More messages exist beyond this page
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
+// Mention kinds: a user named in the content, or everyone through @room.
+const (
+	MentionUser = "user"
+	MentionRoom = "room"
+)
+
+// Mention represents a user being mentioned in a message.
+type Mention struct {
+	ID          int        `json:"id"`
+	MessageID   int        `json:"message_id"`
+	RoomID      int        `json:"room_id"`
+	UserID      int        `json:"user_id"`      // Mentioned user
+	MentionedBy int        `json:"mentioned_by"` // Author of the message, 0 if since removed
+	Kind        string     `json:"kind"`         // user or room
+	ReadAt      *time.Time `json:"read_at,omitempty"`
+	Message     *Message   `json:"message,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// MentionInboxResponse represents a page of the caller's mention inbox.
+type MentionInboxResponse struct {
+	Mentions    []Mention `json:"mentions"`
+	UnreadCount int       `json:"unread_count"` // Unread mentions in the whole inbox
+	HasMore     bool      `json:"has_more"`     // Older mentions exist beyond this page
+}
+
+// MentionReadRequest represents the payload for marking mentions as read.
+// Without IDs every mention in the inbox is marked.
+type MentionReadRequest struct {
+	IDs []int `json:"ids,omitempty"`
+}
+
+// ReactionRequest represents the payload for reacting to a message.
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required"`
+}
+
+// ThreadResponse represents a page of a thread returned in API responses.
+type ThreadResponse struct {
+	Parent    Message   `json:"parent"` // Top-level message that started the thread
+	Replies   []Message `json:"replies"`
+	HasMore   bool      `json:"has_more"`  // More replies exist beyond this page
+	Following bool      `json:"following"` // Whether the caller follows the thread
+}
+
 // MessageCreateRequest represents the payload for sending a message.
 type MessageCreateRequest struct {
 	RoomID  int    `json:"room_id" binding:"required"`
@@ -62,3 +191,33 @@ type MessageResponse struct {
 	Content   string    `json:"content"`
 	CreatedAt time.Time `json:"created_at"`
 }
+
+// MessageUpdateRequest represents the payload for editing a message.
+type MessageUpdateRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// ReadReceipt represents the newest message a user has read in a room.
+type ReadReceipt struct {
+	RoomID            int       `json:"room_id"`
+	UserID            int       `json:"user_id"`
+	LastReadMessageID int       `json:"last_read_message_id"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// ReadRequest represents the payload for marking a room as read up to a message.
+type ReadRequest struct {
+	MessageID int `json:"message_id" binding:"required"`
+}
+
+// RoomSummary represents a room in the caller's room list, with its unread state.
+type RoomSummary struct {
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	Description       string    `json:"description,omitempty"`
+	Kind              string    `json:"kind"`                 // room, direct or group
+	UnreadCount       int       `json:"unread_count"`         // Messages from others after the read marker
+	LastReadMessageID int       `json:"last_read_message_id"` // 0 if nothing has been read yet
+	LastMessage       *Message  `json:"last_message"`         // nil for a room without messages
+	CreatedAt         time.Time `json:"created_at"`
+}
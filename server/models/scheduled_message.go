@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// Statuses of a scheduled message.
+const (
+	ScheduledPending   = "pending"   // Waiting for its send time
+	ScheduledSent      = "sent"      // Posted to the room
+	ScheduledCancelled = "cancelled" // Cancelled by its author
+	ScheduledFailed    = "failed"    // Could not be posted, e.g. the author left the room
+)
+
+// ScheduledMessage represents a message to be posted to a room at a later time.
+type ScheduledMessage struct {
+	ID        int       `json:"id"`
+	RoomID    int       `json:"room_id"`
+	UserID    int       `json:"user_id"`             // Author, who becomes the sender
+	ParentID  *int      `json:"parent_id,omitempty"` // Thread to post the message in
+	Content   string    `json:"content"`
+	SendAt    time.Time `json:"send_at"`
+	Status    string    `json:"status"`
+	MessageID *int      `json:"message_id,omitempty"` // Posted message, once sent
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScheduledMessageRequest represents the payload for scheduling a message.
+type ScheduledMessageRequest struct {
+	Content  string    `json:"content" binding:"required"`
+	SendAt   time.Time `json:"send_at" binding:"required"`
+	ParentID int       `json:"parent_id,omitempty"`
+}
+
+// ScheduledMessageUpdateRequest represents the payload for changing a pending scheduled message.
+type ScheduledMessageUpdateRequest struct {
+	Content *string    `json:"content,omitempty"`
+	SendAt  *time.Time `json:"send_at,omitempty"`
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// MessageSearchQuery holds the filters of a full-text message search.
+type MessageSearchQuery struct {
+	Query  string     // Search terms, in web search syntax ("quoted phrases", -excluded, or)
+	RoomID int        // 0 searches every room the caller can see
+	UserID int        // Author filter, 0 for any author
+	From   *time.Time // Messages sent at or after this time
+	To     *time.Time // Messages sent before this time
+	Global bool       // Search every room regardless of membership (super-admins only)
+	Limit  int
+	Offset int
+}
+
+// MessageSearchResult represents one message matching a search.
+type MessageSearchResult struct {
+	Message Message `json:"message"`
+	Rank    float64 `json:"rank"`    // Relevance, higher is better
+	Snippet string  `json:"snippet"` // Matching fragments with terms wrapped in <mark></mark>; the rest is HTML-escaped message text
+}
+
+// MessageSearchResponse represents a page of search results returned in API responses.
+type MessageSearchResponse struct {
+	Results []MessageSearchResult `json:"results"`
+	HasMore bool                  `json:"has_more"` // More results exist beyond this page
+}
@@ -7,7 +7,7 @@ type User struct {
 	ID        int       `json:"id"`
 	Name      string    `json:"name"`
 	Email     string    `json:"email"`
-	Password  string    `json:"-"` // Exclude from JSON response for security
+	Password  string    `json:"-"`    // Exclude from JSON response for security
 	Role      string    `json:"role"` // User role (e.g., admin, user)
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -37,3 +37,9 @@ type UserResponse struct {
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
+
+// UserPresence represents the live status of a user.
+type UserPresence struct {
+	UserID int    `json:"user_id"`
+	Status string `json:"status"` // online, away or offline
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"chatingApp/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrAttachmentUnavailable is returned when an attachment sent with a message does not exist,
+// was uploaded by someone else or to another room, or was already sent.
+var ErrAttachmentUnavailable = errors.New("attachment is not available")
+
+// attachmentColumns lists the columns read into a models.Attachment, in scanAttachment order
+const attachmentColumns = `id, room_id, message_id, COALESCE(uploader_id, 0), filename, content_type, size,
+	storage_key, COALESCE(thumbnail_key, ''), COALESCE(width, 0), COALESCE(height, 0), created_at`
+
+type AttachmentRepository struct {
+	DB *sql.DB
+}
+
+func NewAttachmentRepository(db *sql.DB) *AttachmentRepository {
+	return &AttachmentRepository{DB: db}
+}
+
+// CreateAttachment inserts a stored upload that is not yet sent with a message
+func (repo *AttachmentRepository) CreateAttachment(attachment *models.Attachment) (*models.Attachment, error) {
+	query := `INSERT INTO attachments (room_id, uploader_id, filename, content_type, size, storage_key, thumbnail_key, width, height)
+			  VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, 0), NULLIF($9, 0))
+			  RETURNING ` + attachmentColumns + `;`
+
+	row := repo.DB.QueryRow(query, attachment.RoomID, attachment.UploaderID, attachment.Filename, attachment.ContentType,
+		attachment.Size, attachment.StorageKey, attachment.ThumbnailKey, attachment.Width, attachment.Height)
+	return scanAttachment(row)
+}
+
+// GetAttachmentByID retrieves an attachment by its ID, or nil if it does not exist
+func (repo *AttachmentRepository) GetAttachmentByID(attachmentID int) (*models.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1;`
+
+	attachment, err := scanAttachment(repo.DB.QueryRow(query, attachmentID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return attachment, err
+}
+
+// GetAttachmentsByMessageIDs retrieves the attachments sent with each of the given messages
+func (repo *AttachmentRepository) GetAttachmentsByMessageIDs(messageIDs []int) (map[int][]models.Attachment, error) {
+	attachments := make(map[int][]models.Attachment)
+	if len(messageIDs) == 0 {
+		return attachments, nil
+	}
+
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE message_id = ANY($1) ORDER BY id;`
+	rows, err := repo.DB.Query(query, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		attachments[*attachment.MessageID] = append(attachments[*attachment.MessageID], *attachment)
+	}
+
+	return attachments, rows.Err()
+}
+
+// scanAttachment reads one row selected with attachmentColumns
+func scanAttachment(row rowScanner) (*models.Attachment, error) {
+	attachment := &models.Attachment{}
+	err := row.Scan(&attachment.ID, &attachment.RoomID, &attachment.MessageID, &attachment.UploaderID,
+		&attachment.Filename, &attachment.ContentType, &attachment.Size, &attachment.StorageKey,
+		&attachment.ThumbnailKey, &attachment.Width, &attachment.Height, &attachment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	attachment.SetURLs()
+	return attachment, nil
+}
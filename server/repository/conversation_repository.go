@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"chatingApp/models"
+
+	"github.com/lib/pq"
+)
+
+type ConversationRepository struct {
+	DB *sql.DB
+}
+
+func NewConversationRepository(db *sql.DB) *ConversationRepository {
+	return &ConversationRepository{DB: db}
+}
+
+// GetOrCreateConversation returns the conversation identified by key, creating it with the
+// users as its members if it does not exist yet. The returned bool reports whether it was
+// created. Returns ErrUserNotFound if one of the users does not exist
+func (repo *ConversationRepository) GetOrCreateConversation(kind, key string, creatorID int, userIDs []int) (*models.Room, bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	users := pq.Array(userIDs)
+	var existing int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM users WHERE id = ANY($1);`, users).Scan(&existing); err != nil {
+		return nil, false, err
+	}
+	if existing != len(userIDs) {
+		return nil, false, ErrUserNotFound
+	}
+
+	// A concurrent request for the same users waits on the dm_key and then creates nothing
+	query := `INSERT INTO rooms (name, description, created_by, visibility, kind, dm_key, created_at, updated_at)
+			  VALUES ('', '', $1, 'private', $2, $3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  ON CONFLICT (dm_key) DO NOTHING
+			  RETURNING id;`
+	var roomID int
+	err = tx.QueryRow(query, creatorID, kind, key).Scan(&roomID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+	created := err == nil
+
+	if created {
+		query = `INSERT INTO room_users (room_id, user_id) SELECT $1, UNNEST($2::int[]);`
+		if _, err := tx.Exec(query, roomID, users); err != nil {
+			return nil, false, err
+		}
+	}
+
+	query = `SELECT ` + roomColumns + ` FROM rooms r WHERE r.dm_key = $1;`
+	room, err := scanRoom(tx.QueryRow(query, key))
+	if err != nil {
+		return nil, false, err
+	}
+
+	return room, created, tx.Commit()
+}
+
+// GetConversationSummaries retrieves the direct and group conversations of a user with their
+// unread count and newest message, most recently active first. A roomID other than 0 limits
+// the result to that conversation
+func (repo *ConversationRepository) GetConversationSummaries(userID, roomID int) ([]models.RoomSummary, error) {
+	return roomSummaries(repo.DB, userID, true, roomID)
+}
+
+// GetParticipants retrieves the members of each of the given rooms, ordered by name
+func (repo *ConversationRepository) GetParticipants(roomIDs []int) (map[int][]models.UserResponse, error) {
+	participants := make(map[int][]models.UserResponse, len(roomIDs))
+	if len(roomIDs) == 0 {
+		return participants, nil
+	}
+
+	query := `SELECT ru.room_id, u.id, u.name, u.email, u.role, u.created_at, u.updated_at
+			  FROM room_users ru JOIN users u ON u.id = ru.user_id
+			  WHERE ru.room_id = ANY($1)
+			  ORDER BY ru.room_id, u.name, u.id;`
+	rows, err := repo.DB.Query(query, pq.Array(roomIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var roomID int
+		var user models.UserResponse
+		err := rows.Scan(&roomID, &user.ID, &user.Name, &user.Email, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		participants[roomID] = append(participants[roomID], user)
+	}
+
+	return participants, rows.Err()
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"chatingApp/models"
+
+	"github.com/lib/pq"
+)
+
+type ExportRepository struct {
+	DB *sql.DB
+}
+
+func NewExportRepository(db *sql.DB) *ExportRepository {
+	return &ExportRepository{DB: db}
+}
+
+// exportJobColumns lists the export_jobs columns read by scanExportJob, in order
+const exportJobColumns = `id, room_id, requested_by, format, from_time, to_time, status,
+	COALESCE(storage_key, ''), COALESCE(size, 0), COALESCE(error, ''), created_at, finished_at`
+
+// StreamRoomMessages calls fn for every message of a room in the given time range, oldest
+// first, with author names resolved. Rows are read one at a time as fn consumes them, so
+// the room is never held in memory as a whole.
+func (repo *ExportRepository) StreamRoomMessages(ctx context.Context, query models.ExportQuery, fn func(*models.ExportMessage) error) error {
+	sqlQuery := `SELECT m.id, m.seq, m.parent_id, m.user_id, COALESCE(u.name, ''), m.content,
+					 ARRAY(SELECT a.filename FROM attachments a WHERE a.message_id = m.id ORDER BY a.id),
+					 m.edited_at, m.deleted_at, m.created_at
+				 FROM messages m
+				 LEFT JOIN users u ON u.id = m.user_id
+				 WHERE m.room_id = $1`
+	args := []interface{}{query.RoomID}
+
+	if query.From != nil {
+		args = append(args, *query.From)
+		sqlQuery += " AND m.created_at >= " + localTimeParam(len(args))
+	}
+	if query.To != nil {
+		args = append(args, *query.To)
+		sqlQuery += " AND m.created_at < " + localTimeParam(len(args))
+	}
+	sqlQuery += " ORDER BY m.seq;"
+
+	rows, err := repo.DB.QueryContext(ctx, sqlQuery, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var message models.ExportMessage
+		if err := rows.Scan(&message.ID, &message.Seq, &message.ParentID, &message.UserID, &message.Author,
+			&message.Content, pq.Array(&message.Attachments), &message.EditedAt, &message.DeletedAt,
+			&message.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(&message); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// CreateExportJob stores a pending background export
+func (repo *ExportRepository) CreateExportJob(job *models.ExportJob) (*models.ExportJob, error) {
+	query := `INSERT INTO export_jobs (room_id, requested_by, format, from_time, to_time)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING ` + exportJobColumns + `;`
+
+	return scanExportJob(repo.DB.QueryRow(query, job.RoomID, job.RequestedBy, job.Format, job.From, job.To))
+}
+
+// GetExportJob retrieves an export job, or nil if it does not exist
+func (repo *ExportRepository) GetExportJob(jobID int) (*models.ExportJob, error) {
+	query := `SELECT ` + exportJobColumns + ` FROM export_jobs WHERE id = $1;`
+
+	job, err := scanExportJob(repo.DB.QueryRow(query, jobID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// ClaimExportJob marks the oldest pending export job as running and returns it, or nil if
+// there is none. A job still running after staleAfter is taken to have been abandoned by
+// an instance that stopped, and is claimed again.
+func (repo *ExportRepository) ClaimExportJob(staleAfter time.Duration) (*models.ExportJob, error) {
+	query := `UPDATE export_jobs SET status = 'running', started_at = NOW()
+			  WHERE id = (
+				  SELECT id FROM export_jobs
+				  WHERE status = 'pending'
+					 OR (status = 'running' AND started_at < NOW() - make_interval(secs => $1))
+				  ORDER BY id
+				  LIMIT 1
+				  FOR UPDATE SKIP LOCKED
+			  )
+			  RETURNING ` + exportJobColumns + `;`
+
+	job, err := scanExportJob(repo.DB.QueryRow(query, staleAfter.Seconds()))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// FinishExportJob records where the transcript of a finished export job is stored
+func (repo *ExportRepository) FinishExportJob(jobID int, storageKey string, size int64) error {
+	query := `UPDATE export_jobs SET status = 'done', storage_key = $2, size = $3, finished_at = CURRENT_TIMESTAMP
+			  WHERE id = $1;`
+	_, err := repo.DB.Exec(query, jobID, storageKey, size)
+	return err
+}
+
+// FailExportJob records why an export job failed
+func (repo *ExportRepository) FailExportJob(jobID int, reason string) error {
+	query := `UPDATE export_jobs SET status = 'failed', error = $2, finished_at = CURRENT_TIMESTAMP
+			  WHERE id = $1;`
+	_, err := repo.DB.Exec(query, jobID, reason)
+	return err
+}
+
+// scanExportJob reads one row selected with exportJobColumns
+func scanExportJob(row rowScanner) (*models.ExportJob, error) {
+	job := &models.ExportJob{}
+	err := row.Scan(&job.ID, &job.RoomID, &job.RequestedBy, &job.Format, &job.From, &job.To, &job.Status,
+		&job.StorageKey, &job.Size, &job.Error, &job.CreatedAt, &job.FinishedAt)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == models.ExportDone {
+		job.DownloadURL = fmt.Sprintf("/exports/%d/download", job.ID)
+	}
+	return job, nil
+}
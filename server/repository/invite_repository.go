@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"chatingApp/models"
+)
+
+var (
+	// ErrInviteNotFound is returned when an invite code does not exist or is meant for another user.
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrInviteUnavailable is returned when an invite was revoked, has expired or is used up.
+	ErrInviteUnavailable = errors.New("invite is no longer valid")
+)
+
+type InviteRepository struct {
+	DB *sql.DB
+}
+
+func NewInviteRepository(db *sql.DB) *InviteRepository {
+	return &InviteRepository{DB: db}
+}
+
+// inviteColumns lists the room_invites columns read by scanInvite, in order
+const inviteColumns = `id, room_id, code, COALESCE(created_by, 0), target_user_id, max_uses, uses,
+	expires_at, revoked_at, created_at`
+
+// CreateInvite stores a new invite. Returns ErrUserNotFound if its target user does not exist
+func (repo *InviteRepository) CreateInvite(invite *models.RoomInvite) (*models.RoomInvite, error) {
+	if invite.TargetUserID != nil {
+		var exists bool
+		err := repo.DB.QueryRow(`SELECT EXISTS (SELECT 1 FROM users WHERE id = $1);`, *invite.TargetUserID).Scan(&exists)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrUserNotFound
+		}
+	}
+
+	query := `INSERT INTO room_invites (room_id, code, created_by, target_user_id, max_uses, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)
+			  RETURNING ` + inviteColumns + `;`
+
+	return scanInvite(repo.DB.QueryRow(query, invite.RoomID, invite.Code, invite.CreatedBy,
+		invite.TargetUserID, invite.MaxUses, invite.ExpiresAt))
+}
+
+// GetRoomInvites retrieves the invites of a room, newest first. With activeOnly, invites that
+// were revoked, have expired or are used up are left out
+func (repo *InviteRepository) GetRoomInvites(roomID int, activeOnly bool) ([]models.RoomInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM room_invites
+			  WHERE room_id = $1 AND (NOT $2 OR (revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+			  AND (max_uses IS NULL OR uses < max_uses)))
+			  ORDER BY id DESC;`
+
+	rows, err := repo.DB.Query(query, roomID, activeOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []models.RoomInvite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, *invite)
+	}
+
+	return invites, rows.Err()
+}
+
+// GetInvite retrieves an invite of a room, or nil if the room has no such invite
+func (repo *InviteRepository) GetInvite(roomID, inviteID int) (*models.RoomInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM room_invites WHERE id = $1 AND room_id = $2;`
+
+	invite, err := scanInvite(repo.DB.QueryRow(query, inviteID, roomID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return invite, err
+}
+
+// GetInviteByCode retrieves an invite by its code, or nil if there is none
+func (repo *InviteRepository) GetInviteByCode(code string) (*models.RoomInvite, error) {
+	query := `SELECT ` + inviteColumns + ` FROM room_invites WHERE code = $1;`
+
+	invite, err := scanInvite(repo.DB.QueryRow(query, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return invite, err
+}
+
+// RevokeInvite revokes an invite of a room so it can no longer be accepted. Revoking an invite
+// twice keeps the first revocation time. Returns nil if the room has no such invite
+func (repo *InviteRepository) RevokeInvite(roomID, inviteID int) (*models.RoomInvite, error) {
+	query := `UPDATE room_invites SET revoked_at = COALESCE(revoked_at, CURRENT_TIMESTAMP)
+			  WHERE id = $1 AND room_id = $2
+			  RETURNING ` + inviteColumns + `;`
+
+	invite, err := scanInvite(repo.DB.QueryRow(query, inviteID, roomID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return invite, err
+}
+
+// GetInviteUses retrieves the users who joined through an invite, in the order they joined
+func (repo *InviteRepository) GetInviteUses(inviteID int) ([]models.InviteUse, error) {
+	query := `SELECT iu.invite_id, iu.user_id, u.name, iu.joined_at
+			  FROM room_invite_uses iu JOIN users u ON u.id = iu.user_id
+			  WHERE iu.invite_id = $1
+			  ORDER BY iu.joined_at, iu.user_id;`
+
+	rows, err := repo.DB.Query(query, inviteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	uses := []models.InviteUse{}
+	for rows.Next() {
+		var use models.InviteUse
+		if err := rows.Scan(&use.InviteID, &use.UserID, &use.UserName, &use.JoinedAt); err != nil {
+			return nil, err
+		}
+		uses = append(uses, use)
+	}
+
+	return uses, rows.Err()
+}
+
+// AcceptInvite adds a user to the room of an invite and counts the use, in one transaction.
+// The returned bool reports whether the user joined; a user already in the room does not use
+// up the invite. Returns ErrInviteNotFound if there is no such invite or it is meant for someone
+// else, and ErrInviteUnavailable if it was revoked, has expired or is used up
+func (repo *InviteRepository) AcceptInvite(code string, userID int) (*models.RoomInvite, bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Lock the invite so concurrent accepts cannot go past max_uses
+	query := `SELECT ` + inviteColumns + ` FROM room_invites WHERE code = $1 FOR UPDATE;`
+	invite, err := scanInvite(tx.QueryRow(query, code))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, false, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if invite.TargetUserID != nil && *invite.TargetUserID != userID {
+		return nil, false, ErrInviteNotFound
+	}
+	if invite.Status != models.InviteActive {
+		return invite, false, ErrInviteUnavailable
+	}
+
+	joined, err := addUserToRoom(tx, invite.RoomID, userID)
+	if err != nil || !joined {
+		return invite, false, err
+	}
+
+	query = `UPDATE room_invites SET uses = uses + 1 WHERE id = $1;`
+	if _, err := tx.Exec(query, invite.ID); err != nil {
+		return nil, false, err
+	}
+	query = `INSERT INTO room_invite_uses (invite_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+	if _, err := tx.Exec(query, invite.ID, userID); err != nil {
+		return nil, false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	invite.Uses++
+	invite.SetStatus(time.Now())
+	return invite, true, nil
+}
+
+// scanInvite scans a row selected with inviteColumns and works out the invite's status
+func scanInvite(row rowScanner) (*models.RoomInvite, error) {
+	invite := &models.RoomInvite{}
+	err := row.Scan(&invite.ID, &invite.RoomID, &invite.Code, &invite.CreatedBy, &invite.TargetUserID,
+		&invite.MaxUses, &invite.Uses, &invite.ExpiresAt, &invite.RevokedAt, &invite.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	invite.SetStatus(time.Now())
+	return invite, nil
+}
@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"chatingApp/models"
+)
+
+// ErrJoinRequestDecided is returned when a join request that is no longer pending is decided on.
+var ErrJoinRequestDecided = errors.New("join request is no longer pending")
+
+type JoinRequestRepository struct {
+	DB *sql.DB
+}
+
+func NewJoinRequestRepository(db *sql.DB) *JoinRequestRepository {
+	return &JoinRequestRepository{DB: db}
+}
+
+// joinRequestColumns lists the columns read by scanJoinRequest, for a room_join_requests table
+// aliased as jr
+const joinRequestColumns = `jr.id, jr.room_id, jr.user_id, (SELECT u.name FROM users u WHERE u.id = jr.user_id),
+	jr.message, jr.status, jr.decided_by, jr.decided_at, jr.created_at`
+
+// CreateJoinRequest asks for a user to join a room. If they already have a pending request for
+// the room that one is returned instead; the returned bool reports whether a new one was made
+func (repo *JoinRequestRepository) CreateJoinRequest(roomID, userID int, message string) (*models.JoinRequest, bool, error) {
+	query := `INSERT INTO room_join_requests AS jr (room_id, user_id, message) VALUES ($1, $2, $3)
+			  ON CONFLICT (room_id, user_id) WHERE status = 'pending' DO NOTHING
+			  RETURNING ` + joinRequestColumns + `;`
+
+	request, err := scanJoinRequest(repo.DB.QueryRow(query, roomID, userID, message))
+	if err == nil {
+		return request, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	query = `SELECT ` + joinRequestColumns + ` FROM room_join_requests jr
+			 WHERE jr.room_id = $1 AND jr.user_id = $2 AND jr.status = 'pending';`
+	request, err = scanJoinRequest(repo.DB.QueryRow(query, roomID, userID))
+	return request, false, err
+}
+
+// GetJoinRequests retrieves the join requests of a room with a status, oldest first. An empty
+// status retrieves them all, newest first
+func (repo *JoinRequestRepository) GetJoinRequests(roomID int, status string) ([]models.JoinRequest, error) {
+	query := `SELECT ` + joinRequestColumns + ` FROM room_join_requests jr
+			  WHERE jr.room_id = $1 AND ($2 = '' OR jr.status = $2)
+			  ORDER BY CASE WHEN $2 = '' THEN -jr.id ELSE jr.id END;`
+
+	rows, err := repo.DB.Query(query, roomID, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []models.JoinRequest{}
+	for rows.Next() {
+		request, err := scanJoinRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, *request)
+	}
+
+	return requests, rows.Err()
+}
+
+// CancelJoinRequest withdraws a user's pending request to join a room. Returns nil if they have none
+func (repo *JoinRequestRepository) CancelJoinRequest(roomID, userID int) (*models.JoinRequest, error) {
+	query := `UPDATE room_join_requests AS jr SET status = 'cancelled', decided_at = CURRENT_TIMESTAMP
+			  WHERE jr.room_id = $1 AND jr.user_id = $2 AND jr.status = 'pending'
+			  RETURNING ` + joinRequestColumns + `;`
+
+	request, err := scanJoinRequest(repo.DB.QueryRow(query, roomID, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return request, err
+}
+
+// DecideJoinRequest approves or denies a pending join request of a room, adding the user to the
+// room on approval, in one transaction. Returns nil if the room has no such request and
+// ErrJoinRequestDecided if it is no longer pending
+func (repo *JoinRequestRepository) DecideJoinRequest(roomID, requestID, deciderID int, approve bool) (*models.JoinRequest, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the request so it cannot be decided twice at once
+	query := `SELECT ` + joinRequestColumns + ` FROM room_join_requests jr
+			  WHERE jr.id = $1 AND jr.room_id = $2 FOR UPDATE;`
+	request, err := scanJoinRequest(tx.QueryRow(query, requestID, roomID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != models.JoinRequestPending {
+		return request, ErrJoinRequestDecided
+	}
+
+	status := models.JoinRequestDenied
+	if approve {
+		status = models.JoinRequestApproved
+		if _, err := addUserToRoom(tx, roomID, request.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	query = `UPDATE room_join_requests AS jr SET status = $1, decided_by = $2, decided_at = CURRENT_TIMESTAMP
+			 WHERE jr.id = $3
+			 RETURNING ` + joinRequestColumns + `;`
+	request, err = scanJoinRequest(tx.QueryRow(query, status, deciderID, requestID))
+	if err != nil {
+		return nil, err
+	}
+
+	return request, tx.Commit()
+}
+
+// scanJoinRequest scans a row selected with joinRequestColumns
+func scanJoinRequest(row rowScanner) (*models.JoinRequest, error) {
+	request := &models.JoinRequest{}
+	err := row.Scan(&request.ID, &request.RoomID, &request.UserID, &request.UserName, &request.Message,
+		&request.Status, &request.DecidedBy, &request.DecidedAt, &request.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
@@ -0,0 +1,268 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"chatingApp/models"
+
+	"github.com/lib/pq"
+)
+
+type RetentionRepository struct {
+	DB *sql.DB
+}
+
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{DB: db}
+}
+
+// GetRoomRetention retrieves a room's own retention settings; nil values mean the room
+// uses the server-wide default. found is false if the room does not exist.
+func (repo *RetentionRepository) GetRoomRetention(roomID int) (days *int, action *string, found bool, err error) {
+	query := `SELECT retention_days, retention_action FROM rooms WHERE id = $1;`
+	err = repo.DB.QueryRow(query, roomID).Scan(&days, &action)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, false, nil
+	}
+	return days, action, err == nil, err
+}
+
+// SetRoomRetention stores a room's retention settings; nil values fall back to the server-wide default
+func (repo *RetentionRepository) SetRoomRetention(roomID int, days *int, action *string) error {
+	query := `UPDATE rooms SET retention_days = $2, retention_action = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1;`
+	_, err := repo.DB.Exec(query, roomID, days, action)
+	return err
+}
+
+// GetExpiringRooms retrieves the effective policy of every room that does not keep its
+// messages forever, given the server-wide default
+func (repo *RetentionRepository) GetExpiringRooms(defaultDays int, defaultAction string) ([]models.RetentionPolicy, error) {
+	query := `SELECT id, COALESCE(retention_days, $1), COALESCE(retention_action, $2), retention_days IS NULL
+			  FROM rooms
+			  WHERE COALESCE(retention_days, $1) > 0
+			  ORDER BY id;`
+
+	rows, err := repo.DB.Query(query, defaultDays, defaultAction)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	policies := []models.RetentionPolicy{}
+	for rows.Next() {
+		var policy models.RetentionPolicy
+		if err := rows.Scan(&policy.RoomID, &policy.RetentionDays, &policy.Action, &policy.Inherited); err != nil {
+			return nil, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, rows.Err()
+}
+
+// PurgeRoomMessages removes up to limit threads of a room whose latest message is older than
+// days: the top-level message goes together with all of its replies, so a thread is only
+// purged once all of it has expired. With archive the messages are moved to archived_messages.
+// It returns how many messages were removed, the storage keys of their attachments, which
+// the caller deletes once the rows are gone, and whether a full batch was removed.
+func (repo *RetentionRepository) PurgeRoomMessages(roomID, days int, archive bool, limit int) (int, []string, bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return 0, nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Stored times are local, like the CURRENT_TIMESTAMP defaults that set them
+	query := `SELECT m.id FROM messages m
+			  WHERE m.room_id = $1 AND m.parent_id IS NULL
+				AND m.created_at < LOCALTIMESTAMP - make_interval(days => $2)
+				AND NOT EXISTS (SELECT 1 FROM messages r WHERE r.parent_id = m.id
+								AND r.created_at >= LOCALTIMESTAMP - make_interval(days => $2))
+			  ORDER BY m.id
+			  LIMIT $3
+			  FOR UPDATE OF m SKIP LOCKED;`
+	rows, err := tx.Query(query, roomID, days, limit)
+	if err != nil {
+		return 0, nil, false, err
+	}
+	var threadIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, nil, false, err
+		}
+		threadIDs = append(threadIDs, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, false, err
+	}
+	if len(threadIDs) == 0 {
+		return 0, nil, false, nil
+	}
+
+	// Attachment rows would go with their messages; their files are removed by the caller
+	query = `DELETE FROM attachments
+			 WHERE message_id IN (SELECT id FROM messages WHERE id = ANY($1) OR parent_id = ANY($1))
+			 RETURNING storage_key, COALESCE(thumbnail_key, '');`
+	rows, err = tx.Query(query, pq.Array(threadIDs))
+	if err != nil {
+		return 0, nil, false, err
+	}
+	var keys []string
+	for rows.Next() {
+		var key, thumbnailKey string
+		if err := rows.Scan(&key, &thumbnailKey); err != nil {
+			rows.Close()
+			return 0, nil, false, err
+		}
+		keys = append(keys, key)
+		if thumbnailKey != "" {
+			keys = append(keys, thumbnailKey)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, false, err
+	}
+
+	query = `DELETE FROM messages WHERE id = ANY($1) OR parent_id = ANY($1);`
+	if archive {
+		query = `WITH removed AS (
+					 DELETE FROM messages WHERE id = ANY($1) OR parent_id = ANY($1)
+					 RETURNING id, room_id, user_id, seq, parent_id, content, edited_at, deleted_at, deleted_by, created_at
+				 )
+				 INSERT INTO archived_messages (id, room_id, user_id, seq, parent_id, content, edited_at, deleted_at, deleted_by, created_at)
+				 SELECT * FROM removed
+				 ON CONFLICT (id) DO NOTHING;`
+	}
+	result, err := tx.Exec(query, pq.Array(threadIDs))
+	if err != nil {
+		return 0, nil, false, err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return 0, nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, false, err
+	}
+	return int(removed), keys, len(threadIDs) == limit, nil
+}
+
+// PurgeSystemLogs deletes up to limit system logs older than days and returns how many were deleted
+func (repo *RetentionRepository) PurgeSystemLogs(days, limit int) (int, error) {
+	query := `DELETE FROM system_logs
+			  WHERE id IN (SELECT id FROM system_logs
+						   WHERE timestamp < LOCALTIMESTAMP - make_interval(days => $1)
+						   ORDER BY id LIMIT $2);`
+	result, err := repo.DB.Exec(query, days, limit)
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	return int(deleted), err
+}
+
+// PurgeExportJobs deletes up to limit export jobs finished longer than ttl ago and returns how
+// many were deleted and the storage keys of their results
+func (repo *RetentionRepository) PurgeExportJobs(ttl time.Duration, limit int) (int, []string, error) {
+	query := `DELETE FROM export_jobs
+			  WHERE id IN (SELECT id FROM export_jobs
+						   WHERE finished_at < LOCALTIMESTAMP - make_interval(secs => $1)
+						   ORDER BY id LIMIT $2)
+			  RETURNING COALESCE(storage_key, '');`
+	rows, err := repo.DB.Query(query, ttl.Seconds(), limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	deleted := 0
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return 0, nil, err
+		}
+		deleted++
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return deleted, keys, rows.Err()
+}
+
+// PurgeUnsentAttachments deletes up to limit attachments uploaded longer than ttl ago and never
+// sent with a message, and returns how many were deleted and the storage keys of their files
+func (repo *RetentionRepository) PurgeUnsentAttachments(ttl time.Duration, limit int) (int, []string, error) {
+	// Rows are re-checked once locked, so an upload being sent right now is either kept or
+	// already gone when its message is stored
+	query := `DELETE FROM attachments
+			  WHERE id IN (SELECT id FROM attachments
+						   WHERE message_id IS NULL AND created_at < LOCALTIMESTAMP - make_interval(secs => $1)
+						   ORDER BY id LIMIT $2)
+				AND message_id IS NULL
+			  RETURNING storage_key, COALESCE(thumbnail_key, '');`
+	rows, err := repo.DB.Query(query, ttl.Seconds(), limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+
+	deleted := 0
+	keys := []string{}
+	for rows.Next() {
+		var key, thumbnailKey string
+		if err := rows.Scan(&key, &thumbnailKey); err != nil {
+			return 0, nil, err
+		}
+		deleted++
+		keys = append(keys, key)
+		if thumbnailKey != "" {
+			keys = append(keys, thumbnailKey)
+		}
+	}
+
+	return deleted, keys, rows.Err()
+}
+
+// RecordPurgeRun stores the outcome of a purge run
+func (repo *RetentionRepository) RecordPurgeRun(run *models.PurgeRun) error {
+	query := `INSERT INTO purge_runs (started_at, finished_at, messages_deleted, messages_archived, system_logs_deleted, exports_expired, uploads_expired, error)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+			  RETURNING id;`
+	return repo.DB.QueryRow(query, run.StartedAt, run.FinishedAt, run.MessagesDeleted,
+		run.MessagesArchived, run.SystemLogsDeleted, run.ExportsExpired, run.UploadsExpired, run.Error).Scan(&run.ID)
+}
+
+// GetPurgeRuns retrieves the latest purge runs, newest first
+func (repo *RetentionRepository) GetPurgeRuns(limit int) ([]models.PurgeRun, error) {
+	query := `SELECT id, started_at, finished_at, messages_deleted, messages_archived, system_logs_deleted, exports_expired, uploads_expired, COALESCE(error, '')
+			  FROM purge_runs
+			  ORDER BY id DESC
+			  LIMIT $1;`
+
+	rows, err := repo.DB.Query(query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []models.PurgeRun{}
+	for rows.Next() {
+		var run models.PurgeRun
+		if err := rows.Scan(&run.ID, &run.StartedAt, &run.FinishedAt, &run.MessagesDeleted,
+			&run.MessagesArchived, &run.SystemLogsDeleted, &run.ExportsExpired, &run.UploadsExpired, &run.Error); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, rows.Err()
+}
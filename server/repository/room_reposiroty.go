@@ -3,14 +3,30 @@ package repository
 import (
 	"database/sql"
 	"errors"
-	"strconv"
+	"fmt"
 	"strings"
+	"time"
 
 	"chatingApp/models"
 
 	"github.com/lib/pq"
 )
 
+var (
+	// ErrPinLimitReached is returned when a room already has the most pinned messages allowed.
+	ErrPinLimitReached = errors.New("room has too many pinned messages")
+	// ErrRoomNotFound is returned when a room being changed does not exist.
+	ErrRoomNotFound = errors.New("room not found")
+	// ErrUserNotFound is returned when a user being added to a room does not exist.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrNotRoomMember is returned when the target of a membership change is not in the room.
+	ErrNotRoomMember = errors.New("user is not a member of the room")
+	// ErrNotRoomOwner is returned when ownership is handed over by someone who does not hold it.
+	ErrNotRoomOwner = errors.New("user is not the owner of the room")
+	// ErrRoomOwner is returned when the owner would be removed or demoted without a transfer.
+	ErrRoomOwner = errors.New("the room owner cannot be removed or demoted")
+)
+
 type RoomRepository struct {
 	DB *sql.DB
 }
@@ -19,36 +35,52 @@ func NewRoomRepository(db *sql.DB) *RoomRepository {
 	return &RoomRepository{DB: db}
 }
 
-// CreateRoom inserts a new chat room into the database and returns the created room
-func (repo *RoomRepository) CreateRoom(room *models.Room) (*models.Room, error) {
-	query := `INSERT INTO rooms (name, description, created_by, room_admins, created_at, updated_at)
-			  VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
-			  RETURNING id, name, description, created_by, room_admins, created_at, updated_at;`
+// roomColumns are the columns scanned by scanRoom, for a rooms table aliased as r
+const roomColumns = `r.id, r.name, r.description, r.created_by,
+	COALESCE((SELECT o.user_id FROM room_users o WHERE o.room_id = r.id AND o.role = 'owner'), 0),
+	r.visibility, r.kind, r.created_at, r.updated_at`
 
-	var roomAdminsStr string
-	err := repo.DB.QueryRow(query, room.Name, room.Description, room.CreatedBy, pq.Array(room.RoomAdmins)).
-		Scan(&room.ID, &room.Name, &room.Description, &room.CreatedBy, &roomAdminsStr, &room.CreatedAt, &room.UpdatedAt)
+// scanRoom scans a row selected with roomColumns
+func scanRoom(row rowScanner) (*models.Room, error) {
+	room := &models.Room{}
+	err := row.Scan(&room.ID, &room.Name, &room.Description, &room.CreatedBy, &room.OwnerID, &room.Visibility, &room.Kind, &room.CreatedAt, &room.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	return room, nil
+}
 
-	// המרת room_admins ממחרוזת ל- []int
-	room.RoomAdmins, err = parseIntArray(roomAdminsStr)
+// CreateRoom inserts a new chat room with its creator as the owner and returns the created room
+func (repo *RoomRepository) CreateRoom(room *models.Room) (*models.Room, error) {
+	tx, err := repo.DB.Begin()
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return room, nil
+	query := `INSERT INTO rooms (name, description, created_by, visibility, created_at, updated_at)
+			  VALUES ($1, $2, $3, $4, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+			  RETURNING id, name, description, created_by, visibility, kind, created_at, updated_at;`
+	err = tx.QueryRow(query, room.Name, room.Description, room.CreatedBy, room.Visibility).
+		Scan(&room.ID, &room.Name, &room.Description, &room.CreatedBy, &room.Visibility, &room.Kind, &room.CreatedAt, &room.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO room_users (room_id, user_id, role) VALUES ($1, $2, 'owner');`
+	if _, err := tx.Exec(query, room.ID, room.CreatedBy); err != nil {
+		return nil, err
+	}
+	room.OwnerID = room.CreatedBy
+
+	return room, tx.Commit()
 }
 
 // GetRoomByID retrieves a chat room by its ID
 func (repo *RoomRepository) GetRoomByID(roomID int) (*models.Room, error) {
-	query := `SELECT id, name, description, created_by, room_admins, created_at, updated_at FROM rooms WHERE id = $1;`
-	row := repo.DB.QueryRow(query, roomID)
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.id = $1;`
 
-	var roomAdminsStr string
-	room := &models.Room{}
-	err := row.Scan(&room.ID, &room.Name, &room.Description, &room.CreatedBy, &roomAdminsStr, &room.CreatedAt, &room.UpdatedAt)
+	room, err := scanRoom(repo.DB.QueryRow(query, roomID))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
@@ -56,48 +88,86 @@ func (repo *RoomRepository) GetRoomByID(roomID int) (*models.Room, error) {
 		return nil, err
 	}
 
-	// המרת room_admins ממחרוזת ל- []int
-	room.RoomAdmins, err = parseIntArray(roomAdminsStr)
-	if err != nil {
-		return nil, err
-	}
-
 	return room, nil
 }
 
-// GetAllRooms retrieves a list of chat rooms from the database
+// GetAllRooms retrieves a list of chat rooms from the database, leaving out direct and group conversations
 func (repo *RoomRepository) GetAllRooms() ([]models.Room, error) {
-	query := `SELECT id, name, description, created_by, room_admins, created_at, updated_at FROM rooms;`
-	rows, err := repo.DB.Query(query)
+	query := `SELECT ` + roomColumns + ` FROM rooms r WHERE r.kind = 'room';`
+	return repo.queryRooms(query)
+}
+
+// GetRoomsByUserID retrieves all chat rooms a user is a member of
+func (repo *RoomRepository) GetRoomsByUserID(userID int) ([]models.Room, error) {
+	query := `SELECT ` + roomColumns + `
+			  FROM rooms r JOIN room_users ru ON ru.room_id = r.id
+			  WHERE ru.user_id = $1 ORDER BY r.id;`
+	return repo.queryRooms(query, userID)
+}
+
+// GetRoomDirectory retrieves a page of the rooms a user can see in the directory, by name: public
+// and request rooms, and private rooms they are in. With all set every room is listed. Direct and
+// group conversations are never listed. The search
+// matches names and descriptions containing it, ignoring case
+func (repo *RoomRepository) GetRoomDirectory(userID int, query models.RoomDirectoryQuery, all bool) ([]models.RoomDirectoryEntry, error) {
+	sqlQuery := `SELECT r.id, r.name, COALESCE(r.description, ''), r.visibility,
+				 (SELECT COUNT(*) FROM room_users c WHERE c.room_id = r.id),
+				 ru.user_id IS NOT NULL,
+				 EXISTS (SELECT 1 FROM room_join_requests jr
+						 WHERE jr.room_id = r.id AND jr.user_id = $1 AND jr.status = 'pending'),
+				 r.created_at
+				 FROM rooms r LEFT JOIN room_users ru ON ru.room_id = r.id AND ru.user_id = $1
+				 WHERE r.kind = 'room' AND ($2 OR r.visibility <> 'private' OR ru.user_id IS NOT NULL)
+				 AND ($3 = '' OR r.name ILIKE '%' || $3 || '%' ESCAPE '\'
+					  OR r.description ILIKE '%' || $3 || '%' ESCAPE '\')
+				 ORDER BY LOWER(r.name), r.id
+				 LIMIT $4 OFFSET $5;`
+
+	rows, err := repo.DB.Query(sqlQuery, userID, all, escapeLike(query.Query), query.Limit, query.Offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var rooms []models.Room
+	entries := []models.RoomDirectoryEntry{}
 	for rows.Next() {
-		var room models.Room
-		var roomAdminsStr string
-
-		err := rows.Scan(&room.ID, &room.Name, &room.Description, &room.CreatedBy, &roomAdminsStr, &room.CreatedAt, &room.UpdatedAt)
+		var entry models.RoomDirectoryEntry
+		err := rows.Scan(&entry.ID, &entry.Name, &entry.Description, &entry.Visibility, &entry.MemberCount,
+			&entry.Member, &entry.Requested, &entry.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		entries = append(entries, entry)
+	}
 
-		room.RoomAdmins, err = parseIntArray(roomAdminsStr)
-		if err != nil {
-			return nil, err
-		}
+	return entries, rows.Err()
+}
 
-		rooms = append(rooms, room)
-	}
+// escapeLike escapes the LIKE wildcards in s so it matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
 
-	if err := rows.Err(); err != nil {
+// queryRooms runs a query selecting roomColumns and collects the rooms
+func (repo *RoomRepository) queryRooms(query string, args ...interface{}) ([]models.Room, error) {
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	return rooms, nil
+	rooms := []models.Room{}
+	for rows.Next() {
+		room, err := scanRoom(rows)
+		if err != nil {
+			return nil, err
+		}
+		rooms = append(rooms, *room)
+	}
+
+	return rooms, rows.Err()
 }
+
 // DeleteRoom removes a chat room by its ID
 func (repo *RoomRepository) DeleteRoom(roomID int) error {
 	query := `DELETE FROM rooms WHERE id = $1;`
@@ -105,39 +175,222 @@ func (repo *RoomRepository) DeleteRoom(roomID int) error {
 	return err
 }
 
-func (repo *RoomRepository) IsUserRoomAdmin(userID, roomID int) (bool, error) {
-	query := `SELECT EXISTS (SELECT 1 FROM rooms WHERE id = $1 AND $2 = ANY(room_admins));`
-	var exists bool
-	err := repo.DB.QueryRow(query, roomID, userID).Scan(&exists)
-	return exists, err
+// GetMemberRole retrieves a user's role in a room, or "" if they are not a member
+func (repo *RoomRepository) GetMemberRole(roomID, userID int) (string, error) {
+	query := `SELECT role FROM room_users WHERE room_id = $1 AND user_id = $2;`
+	var role string
+	err := repo.DB.QueryRow(query, roomID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return role, err
 }
 
-// UpdateRoomAdmins updates the list of admins for a room
-func (repo *RoomRepository) UpdateRoomAdmins(roomID int, roomAdmins []int) error {
-	query := `UPDATE rooms SET room_admins = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2;`
-	_, err := repo.DB.Exec(query, pq.Array(roomAdmins), roomID)
+// lockRoom locks a room row so membership and role changes to it run one at a time
+func lockRoom(tx *sql.Tx, roomID int) error {
+	var id int
+	err := tx.QueryRow(`SELECT id FROM rooms WHERE id = $1 FOR UPDATE;`, roomID).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrRoomNotFound
+	}
 	return err
 }
 
-// UpdateRoomDetails updates only the name and description of a room
-func (repo *RoomRepository) UpdateRoomDetails(roomID int, name, description string) error {
-	query := `UPDATE rooms SET name = $1, description = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3;`
-	_, err := repo.DB.Exec(query, name, description, roomID)
-	return err
+// lockedMemberRole reads a member's role inside a transaction that holds the room lock
+func lockedMemberRole(tx *sql.Tx, roomID, userID int) (string, error) {
+	var role string
+	err := tx.QueryRow(`SELECT role FROM room_users WHERE room_id = $1 AND user_id = $2;`, roomID, userID).Scan(&role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotRoomMember
+	}
+	return role, err
 }
 
-// AddUserToRoom adds a user to a chat room
-func (repo *RoomRepository) AddUserToRoom(roomID, userID int) error {
-	query := `INSERT INTO room_users (room_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`
-	_, err := repo.DB.Exec(query, roomID, userID)
-	return err
+// SetMemberRole changes the role of a member to moderator or member. Returns ErrNotRoomMember if
+// they are not in the room and ErrRoomOwner if they own it
+func (repo *RoomRepository) SetMemberRole(roomID, userID int, role string) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockRoom(tx, roomID); err != nil {
+		return err
+	}
+	current, err := lockedMemberRole(tx, roomID, userID)
+	if err != nil {
+		return err
+	}
+	if current == models.RoomRoleOwner {
+		return ErrRoomOwner
+	}
+
+	query := `UPDATE room_users SET role = $3 WHERE room_id = $1 AND user_id = $2;`
+	if _, err := tx.Exec(query, roomID, userID, role); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-// RemoveUserFromRoom removes a user from a chat room
+// TransferOwnership makes another member the owner of a room; the previous owner stays on as a
+// moderator. Returns ErrNotRoomOwner if ownerID no longer owns the room and ErrNotRoomMember if
+// newOwnerID is not in it
+func (repo *RoomRepository) TransferOwnership(roomID, ownerID, newOwnerID int) error {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockRoom(tx, roomID); err != nil {
+		return err
+	}
+	current, err := lockedMemberRole(tx, roomID, ownerID)
+	if err != nil && !errors.Is(err, ErrNotRoomMember) {
+		return err
+	}
+	if current != models.RoomRoleOwner {
+		return ErrNotRoomOwner
+	}
+	if _, err := lockedMemberRole(tx, roomID, newOwnerID); err != nil {
+		return err
+	}
+	if newOwnerID == ownerID {
+		return tx.Commit()
+	}
+
+	// Demote first: a room may only have one owner at a time
+	query := `UPDATE room_users SET role = 'moderator' WHERE room_id = $1 AND user_id = $2;`
+	if _, err := tx.Exec(query, roomID, ownerID); err != nil {
+		return err
+	}
+	query = `UPDATE room_users SET role = 'owner' WHERE room_id = $1 AND user_id = $2;`
+	if _, err := tx.Exec(query, roomID, newOwnerID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`UPDATE rooms SET updated_at = CURRENT_TIMESTAMP WHERE id = $1;`, roomID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateRoom applies the set fields of an update to a room in one transaction. When Users is set
+// the members of the room become exactly those users, who join as members; ErrRoomOwner is
+// returned if the owner is left out. Returns the IDs of removed members
+func (repo *RoomRepository) UpdateRoom(roomID int, update models.RoomUpdateRequest) ([]int, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockRoom(tx, roomID); err != nil {
+		return nil, err
+	}
+
+	query := `UPDATE rooms SET name = COALESCE($1, name), description = COALESCE($2, description),
+			  visibility = COALESCE($3, visibility), updated_at = CURRENT_TIMESTAMP WHERE id = $4;`
+	if _, err := tx.Exec(query, update.Name, update.Description, update.Visibility, roomID); err != nil {
+		return nil, err
+	}
+
+	removed := []int{}
+	if update.Users == nil {
+		return removed, tx.Commit()
+	}
+	users := pq.Array(*update.Users)
+
+	var existing int
+	if err := tx.QueryRow(`SELECT COUNT(*) FROM users WHERE id = ANY($1);`, users).Scan(&existing); err != nil {
+		return nil, err
+	}
+	if existing != len(*update.Users) {
+		return nil, ErrUserNotFound
+	}
+
+	var dropsOwner bool
+	query = `SELECT EXISTS (SELECT 1 FROM room_users WHERE room_id = $1 AND role = 'owner' AND NOT (user_id = ANY($2)));`
+	if err := tx.QueryRow(query, roomID, users).Scan(&dropsOwner); err != nil {
+		return nil, err
+	}
+	if dropsOwner {
+		return nil, ErrRoomOwner
+	}
+
+	query = `DELETE FROM room_users WHERE room_id = $1 AND NOT (user_id = ANY($2)) RETURNING user_id;`
+	rows, err := tx.Query(query, roomID, users)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		removed = append(removed, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	query = `INSERT INTO room_users (room_id, user_id) SELECT $1, UNNEST($2::int[]) ON CONFLICT DO NOTHING;`
+	if _, err := tx.Exec(query, roomID, users); err != nil {
+		return nil, err
+	}
+
+	return removed, tx.Commit()
+}
+
+// AddUserToRoom adds a user to a chat room as a member; the returned bool reports whether they
+// were not in the room yet. Returns ErrUserNotFound if the user does not exist
+func (repo *RoomRepository) AddUserToRoom(roomID, userID int) (bool, error) {
+	return addUserToRoom(repo.DB, roomID, userID)
+}
+
+// addUserToRoom runs AddUserToRoom on q, so it can be part of a larger transaction
+func addUserToRoom(q querier, roomID, userID int) (bool, error) {
+	query := `WITH target AS (SELECT id FROM users WHERE id = $2),
+			  added AS (
+				INSERT INTO room_users (room_id, user_id) SELECT $1, id FROM target
+				ON CONFLICT DO NOTHING RETURNING user_id
+			  )
+			  SELECT EXISTS (SELECT 1 FROM target), EXISTS (SELECT 1 FROM added);`
+
+	var exists, added bool
+	if err := q.QueryRow(query, roomID, userID).Scan(&exists, &added); err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, ErrUserNotFound
+	}
+	return added, nil
+}
+
+// RemoveUserFromRoom removes a user from a chat room. Returns ErrRoomOwner if they own it and
+// ErrNotRoomMember if they are not in it
 func (repo *RoomRepository) RemoveUserFromRoom(roomID, userID int) error {
-	query := `DELETE FROM room_users WHERE room_id = $1 AND user_id = $2;`
-	_, err := repo.DB.Exec(query, roomID, userID)
-	return err
+	query := `DELETE FROM room_users WHERE room_id = $1 AND user_id = $2 AND role <> 'owner';`
+	result, err := repo.DB.Exec(query, roomID, userID)
+	if err != nil {
+		return err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil || removed > 0 {
+		return err
+	}
+
+	// Nothing was deleted: tell an owner apart from a non-member
+	role, err := repo.GetMemberRole(roomID, userID)
+	if err != nil {
+		return err
+	}
+	if role == models.RoomRoleOwner {
+		return ErrRoomOwner
+	}
+	return ErrNotRoomMember
 }
 
 // IsUserInRoom checks if a user is a member of a chat room
@@ -169,23 +422,700 @@ func (repo *RoomRepository) GetUsersInRoom(roomID int) ([]int, error) {
 	return users, nil
 }
 
-// parseIntArray converts a PostgreSQL array string "{1,2,3}" to a []int slice
-func parseIntArray(pgArray string) ([]int, error) {
-	pgArray = strings.Trim(pgArray, "{}")
-	if pgArray == "" {
-		return []int{}, nil
+// GetRoomMembers retrieves the members of a room: the owner, then moderators, then members,
+// each ordered by name
+func (repo *RoomRepository) GetRoomMembers(roomID int) ([]models.RoomMember, error) {
+	query := `SELECT u.id, u.name, u.email, u.role, u.created_at, u.updated_at, ru.role, ru.joined_at
+			  FROM room_users ru JOIN users u ON u.id = ru.user_id
+			  WHERE ru.room_id = $1
+			  ORDER BY CASE ru.role WHEN 'owner' THEN 0 WHEN 'moderator' THEN 1 ELSE 2 END, u.name, u.id;`
+	rows, err := repo.DB.Query(query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.RoomMember{}
+	for rows.Next() {
+		var member models.RoomMember
+		err := rows.Scan(&member.ID, &member.Name, &member.Email, &member.Role, &member.CreatedAt, &member.UpdatedAt,
+			&member.RoomRole, &member.JoinedAt)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+
+	return members, rows.Err()
+}
+
+// AddMessageToRoom inserts a new message into a chat room and returns the stored message.
+// The room's next sequence number is claimed in the same statement, so numbers never repeat or skip.
+// A non-zero parentID makes the message a reply in that message's thread and bumps its reply count;
+// the returned bool reports whether it is the thread's first reply.
+// The given uploads of the author in the room are attached in the same transaction; if any of
+// them is not available, nothing is stored and ErrAttachmentUnavailable is returned.
+func (repo *RoomRepository) AddMessageToRoom(roomID, userID int, content string, parentID int, attachmentIDs []int) (*models.Message, bool, error) {
+	if len(attachmentIDs) == 0 {
+		return insertMessage(repo.DB, roomID, userID, content, parentID)
+	}
+
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	message, firstReply, err := insertMessage(tx, roomID, userID, content, parentID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	query := `UPDATE attachments SET message_id = $1
+			  WHERE id = ANY($2) AND room_id = $3 AND uploader_id = $4 AND message_id IS NULL
+			  RETURNING ` + attachmentColumns + `;`
+	rows, err := tx.Query(query, message.ID, pq.Array(attachmentIDs), roomID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			rows.Close()
+			return nil, false, err
+		}
+		message.Attachments = append(message.Attachments, *attachment)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if len(message.Attachments) != len(attachmentIDs) {
+		return nil, false, ErrAttachmentUnavailable
+	}
+
+	return message, firstReply, tx.Commit()
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx
+type querier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// insertMessage claims the room's next sequence number and stores a message with it. For a reply
+// it also reports whether the reply is the first in its thread, counted in the same statement
+func insertMessage(q querier, roomID, userID int, content string, parentID int) (*models.Message, bool, error) {
+	query := `WITH next AS (
+				  UPDATE rooms SET last_seq = last_seq + 1 WHERE id = $1 RETURNING last_seq
+			  ), inserted AS (
+				  INSERT INTO messages (room_id, user_id, content, parent_id, seq, created_at)
+				  SELECT $1, $2, $3, NULLIF($4, 0), last_seq, CURRENT_TIMESTAMP FROM next
+				  RETURNING ` + messageColumns + `
+			  ), parent AS (
+				  UPDATE messages SET reply_count = reply_count + 1
+				  WHERE id = $4 AND EXISTS (SELECT 1 FROM inserted)
+				  RETURNING reply_count
+			  )
+			  SELECT ` + messageColumns + `, COALESCE((SELECT reply_count FROM parent), 0) = 1 FROM inserted;`
+
+	message := &models.Message{}
+	var firstReply bool
+	if err := q.QueryRow(query, roomID, userID, content, parentID).Scan(append(messageDest(message), &firstReply)...); err != nil {
+		return nil, false, err
+	}
+	return message, firstReply, nil
+}
+
+// EditMessage replaces the content of a message that is not deleted and records the
+// previous content in its edit history. It returns nil if there is no such message.
+func (repo *RoomRepository) EditMessage(messageID, editorID int, content string) (*models.Message, error) {
+	query := `WITH old AS (
+				  SELECT id, content FROM messages WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+			  ), history AS (
+				  INSERT INTO message_edits (message_id, content, edited_by, edited_at)
+				  SELECT id, content, $3, CURRENT_TIMESTAMP FROM old
+			  )
+			  UPDATE messages SET content = $2, edited_at = CURRENT_TIMESTAMP
+			  FROM old WHERE messages.id = old.id
+			  RETURNING ` + qualifiedMessageColumns + `;`
+
+	message, err := scanMessage(repo.DB.QueryRow(query, messageID, content, editorID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return message, err
+}
+
+// SoftDeleteMessage turns a message into a tombstone: its content is cleared and moved to
+// the edit history, and the row stays in place so sequence numbers and replies keep their
+// anchor. It returns nil if there is no such message or it is already deleted.
+func (repo *RoomRepository) SoftDeleteMessage(messageID, deletedBy int) (*models.Message, error) {
+	query := `WITH old AS (
+				  SELECT id, content FROM messages WHERE id = $1 AND deleted_at IS NULL FOR UPDATE
+			  ), history AS (
+				  INSERT INTO message_edits (message_id, content, edited_by, edited_at)
+				  SELECT id, content, $2, CURRENT_TIMESTAMP FROM old
+			  )
+			  UPDATE messages SET content = '', deleted_at = CURRENT_TIMESTAMP, deleted_by = $2
+			  FROM old WHERE messages.id = old.id
+			  RETURNING ` + qualifiedMessageColumns + `;`
+
+	message, err := scanMessage(repo.DB.QueryRow(query, messageID, deletedBy))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return message, err
+}
+
+// GetMessageEdits retrieves the previous versions of a message, oldest first
+func (repo *RoomRepository) GetMessageEdits(messageID int) ([]models.MessageEdit, error) {
+	query := `SELECT id, message_id, content, COALESCE(edited_by, 0), edited_at FROM message_edits
+			  WHERE message_id = $1 ORDER BY id ASC;`
+	rows, err := repo.DB.Query(query, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edits := []models.MessageEdit{}
+	for rows.Next() {
+		var edit models.MessageEdit
+		if err := rows.Scan(&edit.ID, &edit.MessageID, &edit.Content, &edit.EditedBy, &edit.EditedAt); err != nil {
+			return nil, err
+		}
+		edits = append(edits, edit)
+	}
+
+	return edits, rows.Err()
+}
+
+// FollowThread makes a user follow the thread of a message
+func (repo *RoomRepository) FollowThread(messageID, userID int) error {
+	query := `INSERT INTO thread_followers (message_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING;`
+	_, err := repo.DB.Exec(query, messageID, userID)
+	return err
+}
+
+// UnfollowThread stops a user from following the thread of a message
+func (repo *RoomRepository) UnfollowThread(messageID, userID int) error {
+	query := `DELETE FROM thread_followers WHERE message_id = $1 AND user_id = $2;`
+	_, err := repo.DB.Exec(query, messageID, userID)
+	return err
+}
+
+// IsFollowingThread checks if a user follows the thread of a message
+func (repo *RoomRepository) IsFollowingThread(messageID, userID int) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM thread_followers WHERE message_id = $1 AND user_id = $2);`
+	var exists bool
+	err := repo.DB.QueryRow(query, messageID, userID).Scan(&exists)
+	return exists, err
+}
+
+// GetThreadFollowers retrieves the users following the thread of a message who are still room members
+func (repo *RoomRepository) GetThreadFollowers(messageID int) ([]int, error) {
+	query := `SELECT tf.user_id FROM thread_followers tf
+			  JOIN messages m ON m.id = tf.message_id
+			  JOIN room_users ru ON ru.room_id = m.room_id AND ru.user_id = tf.user_id
+			  WHERE tf.message_id = $1;`
+	rows, err := repo.DB.Query(query, messageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+
+	return users, rows.Err()
+}
+
+// AddReaction records a user's emoji reaction to a message; the returned bool reports
+// whether it is new
+func (repo *RoomRepository) AddReaction(messageID, userID int, emoji string) (bool, error) {
+	query := `INSERT INTO message_reactions (message_id, user_id, emoji) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`
+	result, err := repo.DB.Exec(query, messageID, userID, emoji)
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	return added > 0, err
+}
+
+// RemoveReaction deletes a user's emoji reaction to a message; the returned bool reports
+// whether there was one
+func (repo *RoomRepository) RemoveReaction(messageID, userID int, emoji string) (bool, error) {
+	query := `DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3;`
+	result, err := repo.DB.Exec(query, messageID, userID, emoji)
+	if err != nil {
+		return false, err
 	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
 
-	strValues := strings.Split(pgArray, ",")
-	intValues := make([]int, len(strValues))
+// PinMessage pins a message in its room unless the room already has maxPins pinned
+// messages; the returned bool reports whether it was newly pinned
+func (repo *RoomRepository) PinMessage(roomID, messageID, userID, maxPins int) (bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// Lock the room so concurrent pins cannot both pass the limit
+	if _, err := tx.Exec(`SELECT id FROM rooms WHERE id = $1 FOR UPDATE;`, roomID); err != nil {
+		return false, err
+	}
+
+	var pinned int
+	var alreadyPinned bool
+	query := `SELECT COUNT(*), COALESCE(BOOL_OR(message_id = $2), false) FROM pinned_messages WHERE room_id = $1;`
+	if err := tx.QueryRow(query, roomID, messageID).Scan(&pinned, &alreadyPinned); err != nil {
+		return false, err
+	}
+	if alreadyPinned {
+		return false, nil
+	}
+	if pinned >= maxPins {
+		return false, ErrPinLimitReached
+	}
 
-	for i, str := range strValues {
-		val, err := strconv.Atoi(str)
+	query = `INSERT INTO pinned_messages (message_id, room_id, pinned_by) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING;`
+	result, err := tx.Exec(query, messageID, roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return added > 0, tx.Commit()
+}
+
+// UnpinMessage unpins a message; the returned bool reports whether it was pinned
+func (repo *RoomRepository) UnpinMessage(messageID int) (bool, error) {
+	query := `DELETE FROM pinned_messages WHERE message_id = $1;`
+	result, err := repo.DB.Exec(query, messageID)
+	if err != nil {
+		return false, err
+	}
+	removed, err := result.RowsAffected()
+	return removed > 0, err
+}
+
+// GetPinnedMessages retrieves the pinned messages of a room, most recently pinned first
+func (repo *RoomRepository) GetPinnedMessages(roomID int) ([]models.PinnedMessage, error) {
+	query := `SELECT COALESCE(p.pinned_by, 0), p.pinned_at, ` + qualifiedMessageColumns + `
+			  FROM pinned_messages p
+			  JOIN messages ON messages.id = p.message_id AND messages.deleted_at IS NULL
+			  WHERE p.room_id = $1
+			  ORDER BY p.pinned_at DESC, p.message_id DESC;`
+
+	rows, err := repo.DB.Query(query, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pins := []models.PinnedMessage{}
+	for rows.Next() {
+		var pin models.PinnedMessage
+		dest := []interface{}{&pin.PinnedBy, &pin.PinnedAt}
+		if err := rows.Scan(append(dest, messageDest(&pin.Message)...)...); err != nil {
+			return nil, err
+		}
+		pins = append(pins, pin)
+	}
+
+	return pins, rows.Err()
+}
+
+// GetReactionCounts aggregates the reactions to each of the given messages, per emoji in the
+// order they were first used. Reacted is set on the emojis userID reacted with.
+func (repo *RoomRepository) GetReactionCounts(messageIDs []int, userID int) (map[int][]models.ReactionCount, error) {
+	counts := make(map[int][]models.ReactionCount)
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	query := `SELECT message_id, emoji, COUNT(*), BOOL_OR(user_id = $2) FROM message_reactions
+			  WHERE message_id = ANY($1)
+			  GROUP BY message_id, emoji ORDER BY message_id, MIN(created_at);`
+	rows, err := repo.DB.Query(query, pq.Array(messageIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID int
+		var count models.ReactionCount
+		if err := rows.Scan(&messageID, &count.Emoji, &count.Count, &count.Reacted); err != nil {
+			return nil, err
+		}
+		counts[messageID] = append(counts[messageID], count)
+	}
+
+	return counts, rows.Err()
+}
+
+// ResolveMentionHandles retrieves the members of a room matching any of the given lowercase
+// handles. A handle matches a user's name with spaces removed or the local part of their email.
+func (repo *RoomRepository) ResolveMentionHandles(roomID int, handles []string) ([]int, error) {
+	query := `SELECT u.id FROM users u
+			  JOIN room_users ru ON ru.user_id = u.id AND ru.room_id = $1
+			  WHERE LOWER(REPLACE(u.name, ' ', '')) = ANY($2) OR LOWER(SPLIT_PART(u.email, '@', 1)) = ANY($2);`
+	rows, err := repo.DB.Query(query, roomID, pq.Array(handles))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+
+	return users, rows.Err()
+}
+
+// AddMentions records that a message mentions the given users and returns the new records.
+// Users the message already mentions are skipped.
+func (repo *RoomRepository) AddMentions(message *models.Message, userIDs []int, kind string) ([]models.Mention, error) {
+	query := `INSERT INTO mentions (message_id, room_id, user_id, mentioned_by, kind)
+			  SELECT $1, $2, UNNEST($3::int[]), $4, $5
+			  ON CONFLICT (message_id, user_id) DO NOTHING
+			  RETURNING id, message_id, room_id, user_id, COALESCE(mentioned_by, 0), kind, read_at, created_at;`
+	rows, err := repo.DB.Query(query, message.ID, message.RoomID, pq.Array(userIDs), message.UserID, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []models.Mention{}
+	for rows.Next() {
+		var mention models.Mention
+		err := rows.Scan(&mention.ID, &mention.MessageID, &mention.RoomID, &mention.UserID,
+			&mention.MentionedBy, &mention.Kind, &mention.ReadAt, &mention.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
-		intValues[i] = val
+		mentions = append(mentions, mention)
+	}
+
+	return mentions, rows.Err()
+}
+
+// GetMentionsByUserID retrieves up to limit mentions of a user, newest first, with their
+// messages. before is a mention ID cursor; zero starts from the newest. Mentions in deleted
+// messages or in rooms the user has left are skipped.
+func (repo *RoomRepository) GetMentionsByUserID(userID, before, limit int, unreadOnly bool) ([]models.Mention, error) {
+	query := `SELECT mentions.id, mentions.message_id, mentions.room_id, mentions.user_id,
+				  COALESCE(mentions.mentioned_by, 0), mentions.kind, mentions.read_at, mentions.created_at,
+				  ` + qualifiedMessageColumns + `
+			  FROM mentions
+			  JOIN messages ON messages.id = mentions.message_id AND messages.deleted_at IS NULL
+			  JOIN room_users ru ON ru.room_id = mentions.room_id AND ru.user_id = mentions.user_id
+			  WHERE mentions.user_id = $1`
+	args := []interface{}{userID}
+
+	if before > 0 {
+		args = append(args, before)
+		query += fmt.Sprintf(" AND mentions.id < $%d", len(args))
+	}
+	if unreadOnly {
+		query += " AND mentions.read_at IS NULL"
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY mentions.id DESC LIMIT $%d;", len(args))
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []models.Mention{}
+	for rows.Next() {
+		mention := models.Mention{Message: &models.Message{}}
+		dest := []interface{}{&mention.ID, &mention.MessageID, &mention.RoomID, &mention.UserID,
+			&mention.MentionedBy, &mention.Kind, &mention.ReadAt, &mention.CreatedAt}
+		if err := rows.Scan(append(dest, messageDest(mention.Message)...)...); err != nil {
+			return nil, err
+		}
+		mentions = append(mentions, mention)
 	}
 
-	return intValues, nil
+	return mentions, rows.Err()
+}
+
+// CountUnreadMentions counts the unread mentions GetMentionsByUserID would list for a user
+func (repo *RoomRepository) CountUnreadMentions(userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM mentions
+			  JOIN messages ON messages.id = mentions.message_id AND messages.deleted_at IS NULL
+			  JOIN room_users ru ON ru.room_id = mentions.room_id AND ru.user_id = mentions.user_id
+			  WHERE mentions.user_id = $1 AND mentions.read_at IS NULL;`
+	var count int
+	err := repo.DB.QueryRow(query, userID).Scan(&count)
+	return count, err
+}
+
+// MarkMentionsRead marks a user's mentions as read: the given ones, or all of them if ids is empty
+func (repo *RoomRepository) MarkMentionsRead(userID int, ids []int) error {
+	query := `UPDATE mentions SET read_at = CURRENT_TIMESTAMP
+			  WHERE user_id = $1 AND read_at IS NULL AND (COALESCE(CARDINALITY($2::int[]), 0) = 0 OR id = ANY($2));`
+	_, err := repo.DB.Exec(query, userID, pq.Array(ids))
+	return err
+}
+
+// MarkRoomMentionsRead marks a user's mentions in a room as read up to and including a message
+func (repo *RoomRepository) MarkRoomMentionsRead(userID, roomID, messageID int) error {
+	query := `UPDATE mentions SET read_at = CURRENT_TIMESTAMP
+			  WHERE user_id = $1 AND room_id = $2 AND message_id <= $3 AND read_at IS NULL;`
+	_, err := repo.DB.Exec(query, userID, roomID, messageID)
+	return err
+}
+
+// GetRoomLastSeq returns the sequence number of the newest message in a room
+func (repo *RoomRepository) GetRoomLastSeq(roomID int) (int64, error) {
+	query := `SELECT last_seq FROM rooms WHERE id = $1;`
+	var lastSeq int64
+	err := repo.DB.QueryRow(query, roomID).Scan(&lastSeq)
+	return lastSeq, err
+}
+
+// GetMessagesAfterSeq retrieves up to limit messages of a room with a sequence number above afterSeq, in order
+func (repo *RoomRepository) GetMessagesAfterSeq(roomID int, afterSeq int64, limit int) ([]models.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages
+			  WHERE room_id = $1 AND seq > $2 ORDER BY seq ASC LIMIT $3;`
+	rows, err := repo.DB.Query(query, roomID, afterSeq, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *message)
+	}
+
+	return messages, rows.Err()
+}
+
+// GetMessagesByRoomID retrieves up to limit top-level messages from a chat room, oldest first.
+// before and after are message ID cursors; a zero value leaves that side unbounded.
+// Without an after cursor the newest matching messages are returned.
+func (repo *RoomRepository) GetMessagesByRoomID(roomID, before, after, limit int) ([]models.Message, error) {
+	return repo.getMessagePage(`room_id = $1 AND parent_id IS NULL`, roomID, before, after, limit)
+}
+
+// GetThreadReplies retrieves up to limit replies in the thread of a message, oldest first,
+// with the same cursors as GetMessagesByRoomID.
+func (repo *RoomRepository) GetThreadReplies(parentID, before, after, limit int) ([]models.Message, error) {
+	return repo.getMessagePage(`parent_id = $1`, parentID, before, after, limit)
+}
+
+// getMessagePage retrieves one page of the messages matching filter, which refers to arg as $1
+func (repo *RoomRepository) getMessagePage(filter string, arg, before, after, limit int) ([]models.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE ` + filter
+	args := []interface{}{arg}
+
+	if before > 0 {
+		args = append(args, before)
+		query += fmt.Sprintf(" AND id < $%d", len(args))
+	}
+	if after > 0 {
+		args = append(args, after)
+		query += fmt.Sprintf(" AND id > $%d", len(args))
+	}
+
+	// Page forward from an after cursor, otherwise page backwards from the newest message
+	ascending := after > 0 && before == 0
+	if ascending {
+		query += " ORDER BY id ASC"
+	} else {
+		query += " ORDER BY id DESC"
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(" LIMIT $%d;", len(args))
+
+	rows, err := repo.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		message, err := scanMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, *message)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if !ascending {
+		for i, j := 0, len(messages)-1; i < j; i, j = i+1, j-1 {
+			messages[i], messages[j] = messages[j], messages[i]
+		}
+	}
+
+	return messages, nil
+}
+
+// GetMessageByID retrieves a message by its ID, or nil if it does not exist
+func (repo *RoomRepository) GetMessageByID(messageID int) (*models.Message, error) {
+	query := `SELECT ` + messageColumns + ` FROM messages WHERE id = $1;`
+
+	message, err := scanMessage(repo.DB.QueryRow(query, messageID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// UpdateReadMarker moves a user's read marker in a room forward to messageID.
+// A marker never moves backwards; the returned bool reports whether it moved.
+func (repo *RoomRepository) UpdateReadMarker(roomID, userID, messageID int) (*models.ReadReceipt, bool, error) {
+	query := `INSERT INTO room_read_markers (room_id, user_id, last_read_message_id, updated_at)
+			  VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+			  ON CONFLICT (room_id, user_id) DO UPDATE
+			  SET last_read_message_id = EXCLUDED.last_read_message_id, updated_at = CURRENT_TIMESTAMP
+			  WHERE room_read_markers.last_read_message_id < EXCLUDED.last_read_message_id
+			  RETURNING room_id, user_id, last_read_message_id, updated_at;`
+
+	receipt := &models.ReadReceipt{}
+	err := repo.DB.QueryRow(query, roomID, userID, messageID).
+		Scan(&receipt.RoomID, &receipt.UserID, &receipt.LastReadMessageID, &receipt.UpdatedAt)
+	if err == nil {
+		return receipt, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, false, err
+	}
+
+	// The marker was already further ahead
+	query = `SELECT room_id, user_id, last_read_message_id, updated_at FROM room_read_markers WHERE room_id = $1 AND user_id = $2;`
+	err = repo.DB.QueryRow(query, roomID, userID).
+		Scan(&receipt.RoomID, &receipt.UserID, &receipt.LastReadMessageID, &receipt.UpdatedAt)
+	if err != nil {
+		return nil, false, err
+	}
+	return receipt, false, nil
+}
+
+// GetRoomSummariesByUserID retrieves every room a user is a member of with its unread
+// count and newest message, most recently active first. Direct and group conversations are
+// left out
+func (repo *RoomRepository) GetRoomSummariesByUserID(userID int) ([]models.RoomSummary, error) {
+	return roomSummaries(repo.DB, userID, false, 0)
+}
+
+// roomSummaries runs GetRoomSummariesByUserID, for conversations instead of rooms if set.
+// A roomID other than 0 limits the result to that room
+func roomSummaries(db *sql.DB, userID int, conversations bool, roomID int) ([]models.RoomSummary, error) {
+	query := `SELECT r.id, r.name, r.description, r.kind, r.created_at,
+				  COALESCE(rr.last_read_message_id, 0),
+				  (SELECT COUNT(*) FROM messages m
+				   WHERE m.room_id = r.id AND m.id > COALESCE(rr.last_read_message_id, 0) AND m.user_id <> $1
+				   AND m.parent_id IS NULL AND m.deleted_at IS NULL),
+				  lm.id, lm.user_id, lm.seq, lm.content, lm.edited_at, lm.deleted_at, lm.created_at
+			  FROM rooms r
+			  JOIN room_users ru ON ru.room_id = r.id AND ru.user_id = $1
+			  LEFT JOIN room_read_markers rr ON rr.room_id = r.id AND rr.user_id = $1
+			  LEFT JOIN LATERAL (
+				  SELECT id, user_id, seq, content, edited_at, deleted_at, created_at FROM messages
+				  WHERE room_id = r.id AND parent_id IS NULL ORDER BY id DESC LIMIT 1
+			  ) lm ON true
+			  WHERE (r.kind <> 'room') = $2 AND ($3 = 0 OR r.id = $3)
+			  ORDER BY COALESCE(lm.created_at, r.created_at) DESC;`
+	rows, err := db.Query(query, userID, conversations, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := []models.RoomSummary{}
+	for rows.Next() {
+		var summary models.RoomSummary
+		var description sql.NullString
+		var lastID, lastUserID sql.NullInt64
+		var lastSeq sql.NullInt64
+		var lastContent sql.NullString
+		var lastEditedAt, lastDeletedAt *time.Time
+		var lastCreatedAt sql.NullTime
+
+		err := rows.Scan(&summary.ID, &summary.Name, &description, &summary.Kind, &summary.CreatedAt,
+			&summary.LastReadMessageID, &summary.UnreadCount,
+			&lastID, &lastUserID, &lastSeq, &lastContent, &lastEditedAt, &lastDeletedAt, &lastCreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		summary.Description = description.String
+		if lastID.Valid {
+			summary.LastMessage = &models.Message{
+				ID:        int(lastID.Int64),
+				RoomID:    summary.ID,
+				UserID:    int(lastUserID.Int64),
+				Seq:       lastSeq.Int64,
+				Content:   lastContent.String,
+				EditedAt:  lastEditedAt,
+				DeletedAt: lastDeletedAt,
+				CreatedAt: lastCreatedAt.Time,
+			}
+		}
+		summaries = append(summaries, summary)
+	}
+
+	return summaries, rows.Err()
+}
+
+// messageColumns lists the columns read into a models.Message, in scanMessage order
+const messageColumns = `id, room_id, user_id, seq, parent_id, reply_count, content, edited_at, deleted_at, deleted_by, created_at`
+
+// qualifiedMessageColumns is messageColumns for statements that join another table
+const qualifiedMessageColumns = `messages.id, messages.room_id, messages.user_id, messages.seq, messages.parent_id,
+	messages.reply_count, messages.content, messages.edited_at, messages.deleted_at, messages.deleted_by, messages.created_at`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanMessage reads one row selected with messageColumns
+func scanMessage(row rowScanner) (*models.Message, error) {
+	message := &models.Message{}
+	if err := row.Scan(messageDest(message)...); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// messageDest returns the scan destinations for messageColumns
+func messageDest(message *models.Message) []interface{} {
+	return []interface{}{&message.ID, &message.RoomID, &message.UserID, &message.Seq, &message.ParentID,
+		&message.ReplyCount, &message.Content, &message.EditedAt, &message.DeletedAt, &message.DeletedBy, &message.CreatedAt}
 }
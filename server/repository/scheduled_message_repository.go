@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"chatingApp/models"
+)
+
+type ScheduledMessageRepository struct {
+	DB *sql.DB
+}
+
+func NewScheduledMessageRepository(db *sql.DB) *ScheduledMessageRepository {
+	return &ScheduledMessageRepository{DB: db}
+}
+
+// scheduledMessageColumns lists the scheduled_messages columns read by scanScheduledMessage, in order
+const scheduledMessageColumns = `id, room_id, user_id, parent_id, content, send_at, status, message_id, created_at, updated_at`
+
+// CreateScheduledMessage stores a pending message to be posted at its send time
+func (repo *ScheduledMessageRepository) CreateScheduledMessage(scheduled *models.ScheduledMessage) (*models.ScheduledMessage, error) {
+	query := `INSERT INTO scheduled_messages (room_id, user_id, parent_id, content, send_at)
+			  VALUES ($1, $2, $3, $4, $5)
+			  RETURNING ` + scheduledMessageColumns + `;`
+
+	return scanScheduledMessage(repo.DB.QueryRow(query, scheduled.RoomID, scheduled.UserID,
+		scheduled.ParentID, scheduled.Content, scheduled.SendAt))
+}
+
+// GetPendingByUserID retrieves a user's pending scheduled messages, soonest first.
+// A non-zero roomID keeps only those for that room.
+func (repo *ScheduledMessageRepository) GetPendingByUserID(userID, roomID int) ([]models.ScheduledMessage, error) {
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+			  WHERE user_id = $1 AND status = 'pending' AND ($2 = 0 OR room_id = $2)
+			  ORDER BY send_at, id;`
+
+	rows, err := repo.DB.Query(query, userID, roomID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	scheduled := []models.ScheduledMessage{}
+	for rows.Next() {
+		message, err := scanScheduledMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+		scheduled = append(scheduled, *message)
+	}
+
+	return scheduled, rows.Err()
+}
+
+// UpdatePending changes the content and send time of a user's pending scheduled message.
+// It returns nil if there is no such message or it is no longer pending.
+func (repo *ScheduledMessageRepository) UpdatePending(id, userID int, content string, sendAt time.Time) (*models.ScheduledMessage, error) {
+	// A message being dispatched is locked; once the lock is released it is no longer pending
+	query := `UPDATE scheduled_messages SET content = $3, send_at = $4, updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND user_id = $2 AND status = 'pending'
+			  RETURNING ` + scheduledMessageColumns + `;`
+
+	scheduled, err := scanScheduledMessage(repo.DB.QueryRow(query, id, userID, content, sendAt))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return scheduled, err
+}
+
+// GetPendingByID retrieves a user's pending scheduled message, or nil if there is none
+func (repo *ScheduledMessageRepository) GetPendingByID(id, userID int) (*models.ScheduledMessage, error) {
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+			  WHERE id = $1 AND user_id = $2 AND status = 'pending';`
+
+	scheduled, err := scanScheduledMessage(repo.DB.QueryRow(query, id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return scheduled, err
+}
+
+// CancelPending cancels a user's pending scheduled message; the returned bool reports
+// whether there was one
+func (repo *ScheduledMessageRepository) CancelPending(id, userID int) (bool, error) {
+	query := `UPDATE scheduled_messages SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+			  WHERE id = $1 AND user_id = $2 AND status = 'pending';`
+	result, err := repo.DB.Exec(query, id, userID)
+	if err != nil {
+		return false, err
+	}
+	cancelled, err := result.RowsAffected()
+	return cancelled > 0, err
+}
+
+// DispatchNext posts the scheduled message that has been due the longest. The message is
+// stored and marked sent in one transaction, so it is posted exactly once even across
+// restarts, and rows being dispatched elsewhere are skipped. A message whose author left
+// the room, or whose thread was deleted, is marked failed and returned without a message.
+// The returned bool reports whether the message is the first reply in its thread. It returns
+// nil when nothing is due.
+func (repo *ScheduledMessageRepository) DispatchNext() (*models.ScheduledMessage, *models.Message, bool, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, nil, false, err
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + scheduledMessageColumns + ` FROM scheduled_messages
+			  WHERE status = 'pending' AND send_at <= NOW()
+			  ORDER BY send_at, id
+			  LIMIT 1
+			  FOR UPDATE SKIP LOCKED;`
+	scheduled, err := scanScheduledMessage(tx.QueryRow(query))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, false, nil
+	}
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	var postable bool
+	query = `SELECT EXISTS (SELECT 1 FROM room_users WHERE room_id = $1 AND user_id = $2)
+				  AND NOT EXISTS (SELECT 1 FROM messages WHERE id = $3 AND deleted_at IS NOT NULL);`
+	if err := tx.QueryRow(query, scheduled.RoomID, scheduled.UserID, scheduled.ParentID).Scan(&postable); err != nil {
+		return nil, nil, false, err
+	}
+
+	var message *models.Message
+	var firstReply bool
+	scheduled.Status = models.ScheduledFailed
+	if postable {
+		parentID := 0
+		if scheduled.ParentID != nil {
+			parentID = *scheduled.ParentID
+		}
+		message, firstReply, err = insertMessage(tx, scheduled.RoomID, scheduled.UserID, scheduled.Content, parentID)
+		if err != nil {
+			return nil, nil, false, err
+		}
+		scheduled.Status = models.ScheduledSent
+		scheduled.MessageID = &message.ID
+	}
+
+	query = `UPDATE scheduled_messages SET status = $2, message_id = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $1;`
+	if _, err := tx.Exec(query, scheduled.ID, scheduled.Status, scheduled.MessageID); err != nil {
+		return nil, nil, false, err
+	}
+
+	return scheduled, message, firstReply, tx.Commit()
+}
+
+// scanScheduledMessage reads one row selected with scheduledMessageColumns
+func scanScheduledMessage(row rowScanner) (*models.ScheduledMessage, error) {
+	scheduled := &models.ScheduledMessage{}
+	err := row.Scan(&scheduled.ID, &scheduled.RoomID, &scheduled.UserID, &scheduled.ParentID, &scheduled.Content,
+		&scheduled.SendAt, &scheduled.Status, &scheduled.MessageID, &scheduled.CreatedAt, &scheduled.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return scheduled, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"chatingApp/models"
+)
+
+// searchHeadlineOptions shapes the snippets returned with search results
+const searchHeadlineOptions = `StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5`
+
+// escapedContent is messages.content with HTML special characters escaped, so a snippet's only
+// markup is its <mark> tags. The parser reads the entities as single tokens, which are never highlighted
+const escapedContent = `replace(replace(replace(replace(replace(messages.content,
+	'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;')`
+
+type SearchRepository struct {
+	DB *sql.DB
+}
+
+func NewSearchRepository(db *sql.DB) *SearchRepository {
+	return &SearchRepository{DB: db}
+}
+
+// SearchMessages runs a full-text search over messages that are not deleted, best match
+// first. Unless the query is global, only rooms callerID is a member of are searched.
+func (repo *SearchRepository) SearchMessages(query models.MessageSearchQuery, callerID int) ([]models.MessageSearchResult, error) {
+	sqlQuery := `SELECT ` + qualifiedMessageColumns + `,
+					 ts_rank(messages.search_vector, q) AS rank,
+					 ts_headline('simple', ` + escapedContent + `, q, '` + searchHeadlineOptions + `')
+				 FROM messages, websearch_to_tsquery('simple', $1) q
+				 WHERE messages.search_vector @@ q AND messages.deleted_at IS NULL`
+	args := []interface{}{query.Query}
+
+	if !query.Global {
+		args = append(args, callerID)
+		sqlQuery += fmt.Sprintf(" AND messages.room_id IN (SELECT room_id FROM room_users WHERE user_id = $%d)", len(args))
+	}
+	if query.RoomID > 0 {
+		args = append(args, query.RoomID)
+		sqlQuery += fmt.Sprintf(" AND messages.room_id = $%d", len(args))
+	}
+	if query.UserID > 0 {
+		args = append(args, query.UserID)
+		sqlQuery += fmt.Sprintf(" AND messages.user_id = $%d", len(args))
+	}
+	if query.From != nil {
+		args = append(args, *query.From)
+		sqlQuery += " AND messages.created_at >= " + localTimeParam(len(args))
+	}
+	if query.To != nil {
+		args = append(args, *query.To)
+		sqlQuery += " AND messages.created_at < " + localTimeParam(len(args))
+	}
+
+	args = append(args, query.Limit, query.Offset)
+	sqlQuery += fmt.Sprintf(" ORDER BY rank DESC, messages.id DESC LIMIT $%d OFFSET $%d;", len(args)-1, len(args))
+
+	rows, err := repo.DB.Query(sqlQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.MessageSearchResult{}
+	for rows.Next() {
+		var result models.MessageSearchResult
+		dest := append(messageDest(&result.Message), &result.Rank, &result.Snippet)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+// localTimeParam refers to time parameter n as a time without zone. Stored times are local to
+// the server, and a zone-aware parameter bound straight to a TIMESTAMP would lose its offset
+func localTimeParam(n int) string {
+	return fmt.Sprintf("($%d::timestamptz AT TIME ZONE current_setting('TimeZone'))", n)
+}
@@ -6,7 +6,7 @@ import (
 )
 
 // SetupRoutes configures all application routes
-func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, logHandler *handlers.LogHandler, roomHandler *handlers.RoomHandler, wsHandler *handlers.WebSocketHandler) {
+func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, logHandler *handlers.LogHandler, roomHandler *handlers.RoomHandler, wsHandler *handlers.WebSocketHandler, searchHandler *handlers.SearchHandler, attachmentHandler *handlers.AttachmentHandler, scheduledHandler *handlers.ScheduledMessageHandler, retentionHandler *handlers.RetentionHandler, exportHandler *handlers.ExportHandler, inviteHandler *handlers.InviteHandler, directoryHandler *handlers.RoomDirectoryHandler, conversationHandler *handlers.ConversationHandler) {
 	// User & Log Routes
 	SetupUserRoutes(router, userHandler)
 	SetupLogRoutes(router, logHandler)
@@ -14,4 +14,15 @@ func SetupRoutes(router *gin.Engine, userHandler *handlers.UserHandler, logHandl
 	// Room & WebSocket Routes
 	SetupRoomRoutes(router, roomHandler)
 	SetupWebSocketRoutes(router, wsHandler)
+	SetupSearchRoutes(router, searchHandler)
+	SetupAttachmentRoutes(router, attachmentHandler)
+	SetupScheduledMessageRoutes(router, scheduledHandler)
+	SetupRetentionRoutes(router, retentionHandler)
+	SetupExportRoutes(router, exportHandler)
+	SetupInviteRoutes(router, inviteHandler)
+	SetupRoomDirectoryRoutes(router, directoryHandler)
+	SetupConversationRoutes(router, conversationHandler)
+
+	// Routes scoped to the authenticated user
+	SetupMeRoutes(router, roomHandler)
 }
@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupAttachmentRoutes configures routes for uploading and downloading files.
+func SetupAttachmentRoutes(router *gin.Engine, attachmentHandler *handlers.AttachmentHandler) {
+	router.POST("/rooms/:id/attachments", middleware.AuthMiddleware(), attachmentHandler.UploadAttachment)
+
+	attachmentRoutes := router.Group("/attachments")
+	{
+		attachmentRoutes.GET("/:id", middleware.AuthMiddleware(), attachmentHandler.DownloadAttachment)
+		attachmentRoutes.GET("/:id/thumbnail", middleware.AuthMiddleware(), attachmentHandler.DownloadThumbnail)
+	}
+}
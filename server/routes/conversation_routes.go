@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupConversationRoutes configures routes for direct and group conversations.
+// Their messages go through the room message routes and socket.
+func SetupConversationRoutes(router *gin.Engine, conversationHandler *handlers.ConversationHandler) {
+	conversationRoutes := router.Group("/conversations")
+	{
+		conversationRoutes.POST("/", middleware.AuthMiddleware(), conversationHandler.OpenConversation)
+		conversationRoutes.GET("/", middleware.AuthMiddleware(), conversationHandler.GetConversations)
+	}
+}
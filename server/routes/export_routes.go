@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupExportRoutes configures routes for exporting room transcripts.
+func SetupExportRoutes(router *gin.Engine, exportHandler *handlers.ExportHandler) {
+	router.GET("/rooms/:id/export", middleware.AuthMiddleware(), exportHandler.ExportRoom)
+	router.POST("/rooms/:id/exports", middleware.AuthMiddleware(), exportHandler.CreateExportJob)
+
+	exportRoutes := router.Group("/exports")
+	{
+		exportRoutes.GET("/:id", middleware.AuthMiddleware(), exportHandler.GetExportJob)
+		exportRoutes.GET("/:id/download", middleware.AuthMiddleware(), exportHandler.DownloadExport)
+	}
+}
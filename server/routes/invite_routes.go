@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupInviteRoutes configures routes for creating, managing and accepting room invites.
+func SetupInviteRoutes(router *gin.Engine, inviteHandler *handlers.InviteHandler) {
+	router.POST("/rooms/:id/invites", middleware.AuthMiddleware(), inviteHandler.CreateInvite)
+	router.GET("/rooms/:id/invites", middleware.AuthMiddleware(), inviteHandler.GetRoomInvites)
+	router.DELETE("/rooms/:id/invites/:inviteID", middleware.AuthMiddleware(), inviteHandler.RevokeInvite)
+	router.GET("/rooms/:id/invites/:inviteID/uses", middleware.AuthMiddleware(), inviteHandler.GetInviteUses)
+
+	inviteRoutes := router.Group("/invites")
+	{
+		inviteRoutes.GET("/:code", middleware.AuthMiddleware(), inviteHandler.PreviewInvite)
+		inviteRoutes.POST("/:code/accept", middleware.AuthMiddleware(), inviteHandler.AcceptInvite)
+	}
+}
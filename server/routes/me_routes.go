@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupMeRoutes configures routes scoped to the authenticated user.
+func SetupMeRoutes(router *gin.Engine, roomHandler *handlers.RoomHandler) {
+	meRoutes := router.Group("/me")
+	{
+		meRoutes.GET("/rooms", middleware.AuthMiddleware(), roomHandler.GetMyRooms) // Rooms with unread counts
+		meRoutes.GET("/mentions", middleware.AuthMiddleware(), roomHandler.GetMyMentions)
+		meRoutes.POST("/mentions/read", middleware.AuthMiddleware(), roomHandler.MarkMentionsRead)
+	}
+}
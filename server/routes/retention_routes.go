@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupRetentionRoutes configures routes for room retention policies and purge history.
+func SetupRetentionRoutes(router *gin.Engine, retentionHandler *handlers.RetentionHandler) {
+	router.GET("/rooms/:id/retention", middleware.AuthMiddleware(), retentionHandler.GetRoomRetention)
+	router.PUT("/rooms/:id/retention", middleware.AuthMiddleware(), retentionHandler.SetRoomRetention)
+
+	retentionRoutes := router.Group("/retention")
+	{
+		retentionRoutes.GET("/runs", middleware.AuthMiddleware(), middleware.AdminMiddleware("super-admin"), retentionHandler.GetPurgeRuns)
+	}
+}
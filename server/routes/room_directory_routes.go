@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupRoomDirectoryRoutes configures routes for browsing rooms, joining them and deciding join requests.
+func SetupRoomDirectoryRoutes(router *gin.Engine, directoryHandler *handlers.RoomDirectoryHandler) {
+	router.GET("/rooms/directory", middleware.AuthMiddleware(), directoryHandler.GetDirectory)
+	router.POST("/rooms/:id/join", middleware.AuthMiddleware(), directoryHandler.JoinRoom)
+	router.DELETE("/rooms/:id/join", middleware.AuthMiddleware(), directoryHandler.CancelJoinRequest)
+	router.GET("/rooms/:id/join-requests", middleware.AuthMiddleware(), directoryHandler.GetJoinRequests)
+	router.POST("/rooms/:id/join-requests/:requestID/approve", middleware.AuthMiddleware(), directoryHandler.ApproveJoinRequest)
+	router.POST("/rooms/:id/join-requests/:requestID/deny", middleware.AuthMiddleware(), directoryHandler.DenyJoinRequest)
+}
@@ -15,8 +15,25 @@ func SetupRoomRoutes(router *gin.Engine, roomHandler *handlers.RoomHandler) {
 		roomRoutes.GET("/:id", middleware.AuthMiddleware(), roomHandler.GetRoom)
 		roomRoutes.DELETE("/:id", middleware.AuthMiddleware(), roomHandler.DeleteRoom)
 		roomRoutes.GET("/room/:id", middleware.AuthMiddleware(), roomHandler.IsUserRoomAdmin)
-		// roomRoutes.PUT("/:id", middleware.AuthMiddleware(), roomHandler.UpdateRoomDetails)
-		// roomRoutes.PUT("/:id/admins", middleware.AuthMiddleware(), roomHandler.UpdateRoomAdmins)
-		// roomRoutes.POST("/:id/users", middleware.AuthMiddleware(), roomHandler.AddUserToRoom)
+		roomRoutes.GET("/:id/messages", middleware.AuthMiddleware(), roomHandler.GetMessagesByRoomID)
+		roomRoutes.PATCH("/:id/messages/:messageID", middleware.AuthMiddleware(), roomHandler.EditMessage)
+		roomRoutes.DELETE("/:id/messages/:messageID", middleware.AuthMiddleware(), roomHandler.DeleteMessage)
+		roomRoutes.GET("/:id/messages/:messageID/history", middleware.AuthMiddleware(), roomHandler.GetMessageHistory)
+		roomRoutes.GET("/:id/messages/:messageID/thread", middleware.AuthMiddleware(), roomHandler.GetThread)
+		roomRoutes.POST("/:id/messages/:messageID/follow", middleware.AuthMiddleware(), roomHandler.FollowThread)
+		roomRoutes.DELETE("/:id/messages/:messageID/follow", middleware.AuthMiddleware(), roomHandler.UnfollowThread)
+		roomRoutes.POST("/:id/messages/:messageID/reactions", middleware.AuthMiddleware(), roomHandler.AddReaction)
+		roomRoutes.DELETE("/:id/messages/:messageID/reactions/:emoji", middleware.AuthMiddleware(), roomHandler.RemoveReaction)
+		roomRoutes.POST("/:id/messages/:messageID/pin", middleware.AuthMiddleware(), roomHandler.PinMessage)
+		roomRoutes.DELETE("/:id/messages/:messageID/pin", middleware.AuthMiddleware(), roomHandler.UnpinMessage)
+		roomRoutes.GET("/:id/pins", middleware.AuthMiddleware(), roomHandler.GetPinnedMessages)
+		roomRoutes.GET("/:id/presence", middleware.AuthMiddleware(), roomHandler.GetRoomPresence)
+		roomRoutes.POST("/:id/read", middleware.AuthMiddleware(), roomHandler.MarkRoomRead)
+		roomRoutes.PUT("/:id", middleware.AuthMiddleware(), roomHandler.UpdateRoomDetails)
+		roomRoutes.GET("/:id/users", middleware.AuthMiddleware(), roomHandler.GetUsersInRoom)
+		roomRoutes.POST("/:id/users", middleware.AuthMiddleware(), roomHandler.AddUserToRoom)
+		roomRoutes.DELETE("/:id/users/:userID", middleware.AuthMiddleware(), roomHandler.RemoveUserFromRoom)
+		roomRoutes.PUT("/:id/users/:userID/role", middleware.AuthMiddleware(), roomHandler.SetMemberRole)
+		roomRoutes.POST("/:id/transfer", middleware.AuthMiddleware(), roomHandler.TransferOwnership)
 	}
 }
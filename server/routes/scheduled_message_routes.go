@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupScheduledMessageRoutes configures routes for scheduling messages and managing pending ones.
+func SetupScheduledMessageRoutes(router *gin.Engine, scheduledHandler *handlers.ScheduledMessageHandler) {
+	router.POST("/rooms/:id/scheduled-messages", middleware.AuthMiddleware(), scheduledHandler.ScheduleMessage)
+
+	scheduledRoutes := router.Group("/me/scheduled-messages")
+	{
+		scheduledRoutes.GET("", middleware.AuthMiddleware(), scheduledHandler.GetMyScheduledMessages)
+		scheduledRoutes.PATCH("/:id", middleware.AuthMiddleware(), scheduledHandler.UpdateScheduledMessage)
+		scheduledRoutes.DELETE("/:id", middleware.AuthMiddleware(), scheduledHandler.CancelScheduledMessage)
+	}
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"chatingApp/handlers"
+	"chatingApp/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// SetupSearchRoutes configures routes for searching messages.
+func SetupSearchRoutes(router *gin.Engine, searchHandler *handlers.SearchHandler) {
+	searchRoutes := router.Group("/search")
+	{
+		searchRoutes.GET("/messages", middleware.AuthMiddleware(), searchHandler.SearchMessages)
+	}
+}
@@ -10,6 +10,8 @@ import (
 func SetupWebSocketRoutes(router *gin.Engine, wsHandler *handlers.WebSocketHandler) {
 	wsRoutes := router.Group("/ws")
 	{
+		wsRoutes.GET("", middleware.AuthMiddleware(), wsHandler.HandleMultiplexedConnection)
+		wsRoutes.GET("/stats", middleware.AuthMiddleware(), middleware.AdminMiddleware("admin"), wsHandler.GetStats) // Only admin or higher can access
 		wsRoutes.GET("/:roomID", middleware.AuthMiddleware(), wsHandler.HandleWebSocketConnection)
 	}
 }
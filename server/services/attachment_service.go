@@ -0,0 +1,317 @@
+package services
+
+import (
+	"bytes"
+	"chatingApp/config"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"chatingApp/storage"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers the GIF decoder for thumbnails
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxThumbnailSourcePixels bounds the images decoded for thumbnails, so a small file
+	// that unpacks to a huge bitmap cannot exhaust memory.
+	maxThumbnailSourcePixels = 40_000_000
+	// maxFilenameLength bounds stored file names, in runes.
+	maxFilenameLength = 255
+)
+
+var (
+	// ErrEmptyAttachment is returned when an uploaded file has no content.
+	ErrEmptyAttachment = errors.New("attachment is empty")
+	// ErrAttachmentTooLarge is returned when an uploaded file exceeds ATTACHMENT_MAX_SIZE.
+	ErrAttachmentTooLarge = errors.New("attachment is too large")
+	// ErrAttachmentTypeNotAllowed is returned when an uploaded file is not of an allowed MIME type.
+	ErrAttachmentTypeNotAllowed = errors.New("attachment type is not allowed")
+	// ErrAttachmentNotFound is returned when an attachment does not exist or cannot be seen by the user.
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+// AttachmentService provides uploads and downloads of files shared in rooms.
+type AttachmentService struct {
+	AttachmentRepo *repository.AttachmentRepository
+	RoomRepo       *repository.RoomRepository
+	Permissions    *RoomPermissionService
+	Storage        storage.Storage // Where file contents are kept
+}
+
+// NewAttachmentService creates a new instance of AttachmentService.
+func NewAttachmentService(attachmentRepo *repository.AttachmentRepository, roomRepo *repository.RoomRepository, permissions *RoomPermissionService, fileStorage storage.Storage) *AttachmentService {
+	return &AttachmentService{AttachmentRepo: attachmentRepo, RoomRepo: roomRepo, Permissions: permissions, Storage: fileStorage}
+}
+
+// Upload stores a file uploaded by a room member, with a thumbnail for images. The file
+// is attached to a message later, when the message is sent with its ID. The MIME type
+// is sniffed from the content; the type claimed by the client is ignored.
+func (s *AttachmentService) Upload(ctx context.Context, roomID, userID int, header *multipart.FileHeader) (*models.Attachment, error) {
+	if !s.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+	if header.Size == 0 {
+		return nil, ErrEmptyAttachment
+	}
+	if header.Size > config.AppConfig.AttachmentMaxSize {
+		return nil, ErrAttachmentTooLarge
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Println("❌ Error: Failed to open upload", err)
+		return nil, err
+	}
+	defer file.Close()
+
+	contentType, err := sniffContentType(file)
+	if err != nil {
+		log.Println("❌ Error: Failed to read upload", err)
+		return nil, err
+	}
+	if !isAllowedAttachmentType(contentType) {
+		return nil, ErrAttachmentTypeNotAllowed
+	}
+
+	attachment := &models.Attachment{
+		RoomID:      roomID,
+		UploaderID:  userID,
+		Filename:    sanitizeFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		StorageKey:  fmt.Sprintf("rooms/%d/%s", roomID, randomKey()),
+	}
+
+	if err := s.Storage.Put(ctx, attachment.StorageKey, file, header.Size, contentType); err != nil {
+		log.Println("❌ Error: Failed to store attachment", err)
+		return nil, err
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		s.storeThumbnail(ctx, attachment, file)
+	}
+
+	created, err := s.AttachmentRepo.CreateAttachment(attachment)
+	if err != nil {
+		log.Println("❌ Error: Failed to save attachment", err)
+		s.deleteStored(ctx, attachment)
+		return nil, err
+	}
+	log.Println("✅ Attachment uploaded successfully to room:", roomID)
+	return created, nil
+}
+
+// Open retrieves an attachment, or its thumbnail, for a member of the room it was uploaded to.
+// Until it is sent with a message, only its uploader can open it. The caller closes the returned reader.
+func (s *AttachmentService) Open(ctx context.Context, attachmentID, userID int, thumbnail bool) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.AttachmentRepo.GetAttachmentByID(attachmentID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve attachment", err)
+		return nil, nil, err
+	}
+	// Non-members are told the attachment does not exist rather than that it is forbidden
+	if attachment == nil || !s.Permissions.IsMember(attachment.RoomID, userID) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	// Unsent uploads are private to the uploader
+	if attachment.MessageID == nil && attachment.UploaderID != userID {
+		return nil, nil, ErrAttachmentNotFound
+	}
+
+	// Files go away with the message they were sent with
+	if attachment.MessageID != nil {
+		message, err := s.RoomRepo.GetMessageByID(*attachment.MessageID)
+		if err != nil {
+			log.Println("❌ Error: Failed to retrieve message", err)
+			return nil, nil, err
+		}
+		if message == nil || message.DeletedAt != nil {
+			return nil, nil, ErrAttachmentNotFound
+		}
+	}
+
+	key := attachment.StorageKey
+	if thumbnail {
+		if attachment.ThumbnailKey == "" {
+			return nil, nil, ErrAttachmentNotFound
+		}
+		key = attachment.ThumbnailKey
+	}
+
+	reader, err := s.Storage.Get(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		log.Println("❌ Error: Attachment content is missing from storage:", key)
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to read attachment", err)
+		return nil, nil, err
+	}
+	return attachment, reader, nil
+}
+
+// storeThumbnail records an image's dimensions and stores a thumbnail of it. Images that
+// cannot be decoded, such as WebP, are kept without one.
+func (s *AttachmentService) storeThumbnail(ctx context.Context, attachment *models.Attachment, file io.ReadSeeker) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return
+	}
+	attachment.Width, attachment.Height = cfg.Width, cfg.Height
+	if cfg.Width*cfg.Height > maxThumbnailSourcePixels {
+		return
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return
+	}
+
+	// JPEG photos stay JPEG; PNG keeps the transparency of PNG and GIF images
+	var buf bytes.Buffer
+	thumb := resizeToFit(img, config.AppConfig.ThumbnailSize)
+	thumbType := "image/png"
+	if attachment.ContentType == "image/jpeg" {
+		thumbType = "image/jpeg"
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+	} else {
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to encode thumbnail", err)
+		return
+	}
+
+	key := attachment.StorageKey + "-thumb"
+	if err := s.Storage.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len()), thumbType); err != nil {
+		log.Println("❌ Error: Failed to store thumbnail", err)
+		return
+	}
+	attachment.ThumbnailKey = key
+}
+
+// deleteStored removes the stored contents of an attachment that could not be saved.
+func (s *AttachmentService) deleteStored(ctx context.Context, attachment *models.Attachment) {
+	for _, key := range []string{attachment.StorageKey, attachment.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := s.Storage.Delete(ctx, key); err != nil {
+			log.Println("❌ Error: Failed to delete stored attachment", err)
+		}
+	}
+}
+
+// resizeToFit scales an image down so its longest side is at most size pixels, averaging
+// the source pixels that fall into each thumbnail pixel. Smaller images are kept as they are.
+func resizeToFit(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	sw, sh := bounds.Dx(), bounds.Dy()
+	if sw <= size && sh <= size {
+		return src
+	}
+
+	tw, th := size, sh*size/sw
+	if sh > sw {
+		tw, th = sw*size/sh, size
+	}
+	tw, th = max(tw, 1), max(th, 1)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := bounds.Min.Y+y*sh/th, bounds.Min.Y+(y+1)*sh/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := bounds.Min.X+x*sw/tw, bounds.Min.X+(x+1)*sw/tw
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := src.At(sx, sy).RGBA()
+					r, g, b, a, n = r+uint64(pr), g+uint64(pg), b+uint64(pb), a+uint64(pa), n+1
+				}
+			}
+			// Average in premultiplied space, then convert back
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
+
+// sniffContentType detects the MIME type of a file from its first bytes and rewinds it.
+func sniffContentType(file io.ReadSeeker) (string, error) {
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(head[:n])
+	if i := strings.IndexByte(contentType, ';'); i >= 0 {
+		contentType = contentType[:i]
+	}
+	return contentType, nil
+}
+
+// isAllowedAttachmentType checks a MIME type against ATTACHMENT_ALLOWED_TYPES.
+func isAllowedAttachmentType(contentType string) bool {
+	for _, allowed := range config.AppConfig.AttachmentAllowedTypes {
+		if strings.EqualFold(allowed, contentType) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitizeFilename keeps the last element of a client-supplied file name, without control characters.
+func sanitizeFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name)
+
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = string(runes[:maxFilenameLength])
+	}
+	if name == "" || name == "." || name == ".." || name == "/" {
+		return "file"
+	}
+	return name
+}
+
+// randomKey returns a random hex name for a stored object.
+func randomKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal("❌ Error: Failed to generate storage key:", err)
+	}
+	return hex.EncodeToString(b)
+}
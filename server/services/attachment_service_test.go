@@ -0,0 +1,244 @@
+package services
+
+import (
+	"bytes"
+	"chatingApp/config"
+	"chatingApp/dbtest"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"chatingApp/storage"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"report.pdf", "report.pdf"},
+		{"../../etc/passwd", "passwd"},
+		{"/absolute/path/photo.jpg", "photo.jpg"},
+		{`C:\Users\me\Desktop\notes.txt`, "notes.txt"},
+		{"dir/", "dir"},
+		{"bad\x00na\nme\r.txt", "badname.txt"},
+		{"", "file"},
+		{".", "file"},
+		{"..", "file"},
+		{"/", "file"},
+		{"a/..", "file"},
+		{"\x07", "file"},
+		{"résumé.txt", "résumé.txt"},
+		{strings.Repeat("é", maxFilenameLength+10), strings.Repeat("é", maxFilenameLength)},
+	}
+
+	for _, tt := range tests {
+		if got := sanitizeFilename(tt.name); got != tt.want {
+			t.Errorf("sanitizeFilename(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSniffContentType(t *testing.T) {
+	var pngFile bytes.Buffer
+	if err := png.Encode(&pngFile, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		content []byte
+		want    string
+	}{
+		{"png", pngFile.Bytes(), "image/png"},
+		{"jpeg", []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00"), "image/jpeg"},
+		{"pdf", []byte("%PDF-1.7\n"), "application/pdf"},
+		{"zip", []byte("PK\x03\x04rest"), "application/zip"},
+		{"text drops the charset", []byte("just some notes"), "text/plain"},
+		{"html", []byte("<!DOCTYPE html><script>alert(1)</script>"), "text/html"},
+		{"empty", nil, "text/plain"},
+		{"binary", []byte{0x00, 0x01, 0x02, 0x03}, "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := bytes.NewReader(tt.content)
+			got, err := sniffContentType(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("sniffContentType = %q, want %q", got, tt.want)
+			}
+
+			// The file is rewound for the upload
+			rest, _ := io.ReadAll(file)
+			if !bytes.Equal(rest, tt.content) {
+				t.Error("file was not rewound")
+			}
+		})
+	}
+}
+
+func TestIsAllowedAttachmentType(t *testing.T) {
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig = &config.Config{AttachmentAllowedTypes: []string{"image/png", "Application/PDF"}}
+
+	tests := map[string]bool{
+		"image/png":                true,
+		"IMAGE/PNG":                true,
+		"application/pdf":          true,
+		"text/html":                false,
+		"image/svg+xml":            false,
+		"application/octet-stream": false,
+		"":                         false,
+	}
+	for contentType, want := range tests {
+		if got := isAllowedAttachmentType(contentType); got != want {
+			t.Errorf("isAllowedAttachmentType(%q) = %v, want %v", contentType, got, want)
+		}
+	}
+}
+
+func TestResizeToFit(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		size          int
+		wantW, wantH  int
+	}{
+		{"smaller is kept", 100, 50, 320, 100, 50},
+		{"exact size is kept", 320, 320, 320, 320, 320},
+		{"landscape", 1000, 500, 100, 100, 50},
+		{"portrait", 500, 1000, 100, 50, 100},
+		{"square", 640, 640, 320, 320, 320},
+		{"very thin keeps a pixel", 10000, 10, 100, 100, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewNRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := resizeToFit(src, tt.size).Bounds()
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Errorf("resizeToFit(%dx%d, %d) = %dx%d, want %dx%d",
+					tt.width, tt.height, tt.size, got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestResizeToFitAveragesPixels(t *testing.T) {
+	// Left half black, right half white, offset from the origin
+	src := image.NewNRGBA(image.Rect(10, 10, 14, 12))
+	for y := 10; y < 12; y++ {
+		for x := 10; x < 14; x++ {
+			if x >= 12 {
+				src.Set(x, y, color.White)
+			} else {
+				src.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	dst := resizeToFit(src, 2)
+	if b := dst.Bounds(); b.Dx() != 2 || b.Dy() != 1 {
+		t.Fatalf("size = %dx%d, want 2x1", b.Dx(), b.Dy())
+	}
+	if r, _, _, _ := dst.At(0, 0).RGBA(); r != 0 {
+		t.Errorf("left pixel red = %d, want 0", r)
+	}
+	if r, _, _, a := dst.At(1, 0).RGBA(); r != 0xFFFF || a != 0xFFFF {
+		t.Errorf("right pixel = %d/%d, want opaque white", r, a)
+	}
+
+	// A half transparent average keeps its colour instead of darkening
+	half := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	half.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	half.Set(1, 0, color.NRGBA{})
+	px := color.NRGBAModel.Convert(resizeToFit(half, 1).At(0, 0)).(color.NRGBA)
+	if px.R < 250 || px.A < 120 || px.A > 135 {
+		t.Errorf("averaged pixel = %+v, want half transparent red", px)
+	}
+}
+
+func TestUnsentAttachmentsArePrivateAndExpire(t *testing.T) {
+	env := newTestEnv(t)
+	owner, member := env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, member)
+
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachments := NewAttachmentService(repository.NewAttachmentRepository(env.db), env.roomRepo, env.permissions, store)
+
+	upload := func(key string) int {
+		t.Helper()
+		if err := store.Put(context.Background(), key, strings.NewReader(key), int64(len(key)), "text/plain"); err != nil {
+			t.Fatal(err)
+		}
+		attachment, err := attachments.AttachmentRepo.CreateAttachment(&models.Attachment{RoomID: roomID, UploaderID: owner,
+			Filename: key + ".txt", ContentType: "text/plain", Size: int64(len(key)), StorageKey: key})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return attachment.ID
+	}
+	canOpen := func(attachmentID, userID int) bool {
+		t.Helper()
+		_, reader, err := attachments.Open(context.Background(), attachmentID, userID, false)
+		if errors.Is(err, ErrAttachmentNotFound) {
+			return false
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader.Close()
+		return true
+	}
+
+	// Until it is sent, only the uploader sees an upload
+	sentID := upload("sent")
+	if !canOpen(sentID, owner) || canOpen(sentID, member) {
+		t.Error("unsent upload: want only the uploader to open it")
+	}
+	if _, err := env.rooms.AddMessageToRoom(roomID, owner, "file", 0, []int{sentID}); err != nil {
+		t.Fatal(err)
+	}
+	if !canOpen(sentID, member) {
+		t.Error("sent upload: members cannot open it")
+	}
+
+	staleID, freshID := upload("stale"), upload("fresh")
+	dbtest.Exec(t, env.db, fmt.Sprintf(`UPDATE attachments SET created_at = LOCALTIMESTAMP - INTERVAL '2 days' WHERE id IN (%d, %d);`, staleID, sentID))
+
+	defaultTTL := config.AppConfig.AttachmentUnsentTTL
+	config.AppConfig.AttachmentUnsentTTL = 24 * time.Hour
+	t.Cleanup(func() { config.AppConfig.AttachmentUnsentTTL = defaultTTL })
+	retention := NewRetentionService(repository.NewRetentionRepository(env.db), env.permissions, store)
+	run := &models.PurgeRun{}
+	if err := retention.purgeUploads(run); err != nil {
+		t.Fatal(err)
+	}
+	if run.UploadsExpired != 1 {
+		t.Errorf("%d uploads expired, want 1", run.UploadsExpired)
+	}
+
+	for id, want := range map[int]bool{sentID: true, staleID: false, freshID: true} {
+		if got := canOpen(id, owner); got != want {
+			t.Errorf("attachment %d can be opened: %t, want %t", id, got, want)
+		}
+	}
+	if _, err := store.Get(context.Background(), "stale"); !errors.Is(err, storage.ErrNotFound) {
+		t.Errorf("file of the expired upload: got %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"chatingApp/config"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrConversationTooSmall is returned when a conversation would have no one but its creator.
+	ErrConversationTooSmall = errors.New("a conversation needs at least one other user")
+	// ErrConversationTooLarge is returned when a group conversation would exceed DM_MAX_PARTICIPANTS.
+	ErrConversationTooLarge = errors.New("too many participants for a conversation")
+	// ErrConversationMembership is returned when the participants of a direct or group
+	// conversation would change; they are fixed when it is created.
+	ErrConversationMembership = errors.New("the participants of a conversation cannot change")
+)
+
+// ConversationService provides direct and group conversations between users. Conversations are
+// rooms without an owner, so their messages use the room message and socket pipeline.
+type ConversationService struct {
+	ConversationRepo *repository.ConversationRepository
+}
+
+// NewConversationService creates a new instance of ConversationService.
+func NewConversationService(conversationRepo *repository.ConversationRepository) *ConversationService {
+	return &ConversationService{ConversationRepo: conversationRepo}
+}
+
+// OpenConversation returns the conversation between the creator and the given users, creating it
+// on first use. The same set of users always gets the same conversation: direct between two of
+// them and group for more. The returned bool reports whether it was created.
+func (s *ConversationService) OpenConversation(creatorID int, userIDs []int) (*models.Conversation, bool, error) {
+	participants := uniqueIDs(append([]int{creatorID}, userIDs...))
+	if len(participants) < 2 {
+		return nil, false, ErrConversationTooSmall
+	}
+	if len(participants) > config.AppConfig.ConversationMaxParticipants {
+		return nil, false, ErrConversationTooLarge
+	}
+
+	kind := models.RoomKindGroup
+	if len(participants) == 2 {
+		kind = models.RoomKindDirect
+	}
+
+	room, created, err := s.ConversationRepo.GetOrCreateConversation(kind, conversationKey(participants), creatorID, participants)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, false, ErrUserNotFound
+		}
+		log.Println("❌ Error: Failed to open conversation", err)
+		return nil, false, err
+	}
+	if created {
+		log.Println("✅ Conversation created successfully:", room.ID, kind)
+	}
+
+	conversations, err := s.getConversations(creatorID, room.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(conversations) == 0 {
+		// The conversation exists but the creator's account was removed from it meanwhile
+		return nil, false, ErrUserNotInRoom
+	}
+	return &conversations[0], created, nil
+}
+
+// GetConversations retrieves the direct and group conversations of a user with their
+// participants, unread counts and last messages, most recently active first.
+func (s *ConversationService) GetConversations(userID int) ([]models.Conversation, error) {
+	return s.getConversations(userID, 0)
+}
+
+// getConversations runs GetConversations, for a single conversation if roomID is not 0.
+func (s *ConversationService) getConversations(userID, roomID int) ([]models.Conversation, error) {
+	summaries, err := s.ConversationRepo.GetConversationSummaries(userID, roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve conversations", err)
+		return nil, err
+	}
+
+	roomIDs := make([]int, len(summaries))
+	for i, summary := range summaries {
+		roomIDs[i] = summary.ID
+	}
+	participants, err := s.ConversationRepo.GetParticipants(roomIDs)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve conversation participants", err)
+		return nil, err
+	}
+
+	conversations := make([]models.Conversation, len(summaries))
+	for i, summary := range summaries {
+		conversations[i] = models.Conversation{RoomSummary: summary, Participants: participants[summary.ID]}
+	}
+	return conversations, nil
+}
+
+// conversationKey identifies a set of participants regardless of their order.
+func conversationKey(participants []int) string {
+	sorted := append([]int(nil), participants...)
+	sort.Ints(sorted)
+
+	ids := make([]string, len(sorted))
+	for i, id := range sorted {
+		ids[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ids, ",")
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"chatingApp/config"
+	"errors"
+	"testing"
+)
+
+func TestConversationKey(t *testing.T) {
+	tests := []struct {
+		participants []int
+		want         string
+	}{
+		{[]int{1, 2}, "1,2"},
+		{[]int{2, 1}, "1,2"},
+		{[]int{10, 9, 100}, "9,10,100"},
+		{[]int{42}, "42"},
+	}
+
+	for _, tt := range tests {
+		if got := conversationKey(tt.participants); got != tt.want {
+			t.Errorf("conversationKey(%v) = %q, want %q", tt.participants, got, tt.want)
+		}
+	}
+}
+
+func TestConversationKeyDedupesParticipants(t *testing.T) {
+	// OpenConversation adds the creator and drops repeats before building the key, so every
+	// way of naming the same users opens the same conversation
+	creator := 3
+	requests := [][]int{{1, 2}, {2, 1}, {1, 2, 2}, {3, 2, 1}, {1, 3, 1, 2}}
+
+	want := conversationKey(uniqueIDs(append([]int{creator}, requests[0]...)))
+	for _, userIDs := range requests[1:] {
+		if got := conversationKey(uniqueIDs(append([]int{creator}, userIDs...))); got != want {
+			t.Errorf("key for %v = %q, want %q", userIDs, got, want)
+		}
+	}
+
+	// The caller's slice is left in its order
+	participants := []int{5, 4}
+	conversationKey(participants)
+	if participants[0] != 5 || participants[1] != 4 {
+		t.Errorf("conversationKey reordered its argument: %v", participants)
+	}
+}
+
+func TestOpenConversationChecksSize(t *testing.T) {
+	previous := config.AppConfig
+	t.Cleanup(func() { config.AppConfig = previous })
+	config.AppConfig = &config.Config{ConversationMaxParticipants: 3}
+
+	// Both are rejected before the repository is touched
+	s := &ConversationService{}
+	tests := []struct {
+		userIDs []int
+		want    error
+	}{
+		{[]int{1}, ErrConversationTooSmall},
+		{[]int{1, 1}, ErrConversationTooSmall},
+		{[]int{2, 3, 4}, ErrConversationTooLarge},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := s.OpenConversation(1, tt.userIDs); !errors.Is(err, tt.want) {
+			t.Errorf("OpenConversation(1, %v) = %v, want %v", tt.userIDs, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"chatingApp/models"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// transcriptTimeLayout is how times are shown in the CSV, HTML and text transcripts.
+const transcriptTimeLayout = "2006-01-02 15:04:05"
+
+// transcriptWriter renders a room transcript one message at a time.
+type transcriptWriter interface {
+	begin(room *models.Room, query models.ExportQuery) error
+	message(m *models.ExportMessage) error
+	end() error
+}
+
+// newTranscriptWriter returns the writer for a format, or nil for an unknown one.
+func newTranscriptWriter(format string, w io.Writer) transcriptWriter {
+	switch format {
+	case models.ExportJSON:
+		return &jsonTranscript{w: w}
+	case models.ExportCSV:
+		return &csvTranscript{w: csv.NewWriter(w)}
+	case models.ExportHTML:
+		return &htmlTranscript{w: w}
+	case models.ExportText:
+		return &textTranscript{w: w}
+	default:
+		return nil
+	}
+}
+
+// ExportContentType is the MIME type a transcript format is served with.
+func ExportContentType(format string) string {
+	switch format {
+	case models.ExportJSON:
+		return "application/json"
+	case models.ExportCSV:
+		return "text/csv; charset=utf-8"
+	case models.ExportHTML:
+		return "text/html; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// jsonTranscript writes {"room": ..., "messages": [...]}, one array element per message.
+type jsonTranscript struct {
+	w     io.Writer
+	count int
+}
+
+func (t *jsonTranscript) begin(room *models.Room, query models.ExportQuery) error {
+	header, err := json.Marshal(struct {
+		RoomID     int        `json:"room_id"`
+		RoomName   string     `json:"room_name"`
+		From       *time.Time `json:"from,omitempty"`
+		To         *time.Time `json:"to,omitempty"`
+		ExportedAt time.Time  `json:"exported_at"`
+	}{room.ID, room.Name, query.From, query.To, time.Now()})
+	if err != nil {
+		return err
+	}
+	// Reopen the header object to append the messages array to it
+	_, err = fmt.Fprintf(t.w, "%s,\"messages\":[", header[:len(header)-1])
+	return err
+}
+
+func (t *jsonTranscript) message(m *models.ExportMessage) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if t.count > 0 {
+		if _, err := io.WriteString(t.w, ",\n"); err != nil {
+			return err
+		}
+	}
+	t.count++
+	_, err = t.w.Write(data)
+	return err
+}
+
+func (t *jsonTranscript) end() error {
+	_, err := io.WriteString(t.w, "]}\n")
+	return err
+}
+
+// csvTranscript writes one row per message under a header row.
+type csvTranscript struct {
+	w *csv.Writer
+}
+
+func (t *csvTranscript) begin(room *models.Room, query models.ExportQuery) error {
+	return t.w.Write([]string{"id", "seq", "parent_id", "user_id", "author", "created_at", "edited_at", "deleted_at", "content", "attachments"})
+}
+
+func (t *csvTranscript) message(m *models.ExportMessage) error {
+	parentID := ""
+	if m.ParentID != nil {
+		parentID = strconv.Itoa(*m.ParentID)
+	}
+	return t.w.Write([]string{
+		strconv.Itoa(m.ID),
+		strconv.FormatInt(m.Seq, 10),
+		parentID,
+		strconv.Itoa(m.UserID),
+		csvSafe(m.Author),
+		m.CreatedAt.Format(transcriptTimeLayout),
+		formatOptionalTime(m.EditedAt),
+		formatOptionalTime(m.DeletedAt),
+		csvSafe(m.Content),
+		csvSafe(strings.Join(m.Attachments, "; ")),
+	})
+}
+
+func (t *csvTranscript) end() error {
+	t.w.Flush()
+	return t.w.Error()
+}
+
+// csvSafe keeps spreadsheets from running user text that looks like a formula.
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// htmlTranscript writes a standalone HTML page with every user-supplied string escaped.
+type htmlTranscript struct {
+	w io.Writer
+}
+
+func (t *htmlTranscript) begin(room *models.Room, query models.ExportQuery) error {
+	title := html.EscapeString(room.Name)
+	_, err := fmt.Fprintf(t.w, `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.message { margin: 0.5em 0; }
+.reply { margin-left: 2em; }
+.meta { color: #666; font-size: 0.85em; }
+.deleted { color: #999; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>%s</h1>
+<p class="meta">Exported %s</p>
+`, title, title, html.EscapeString(time.Now().Format(transcriptTimeLayout)))
+	return err
+}
+
+func (t *htmlTranscript) message(m *models.ExportMessage) error {
+	class := "message"
+	if m.ParentID != nil {
+		class += " reply"
+	}
+
+	content := strings.ReplaceAll(html.EscapeString(m.Content), "\n", "<br>")
+	if m.DeletedAt != nil {
+		content = `<span class="deleted">message deleted</span>`
+	}
+
+	meta := html.EscapeString(m.CreatedAt.Format(transcriptTimeLayout))
+	if m.EditedAt != nil {
+		meta += " (edited)"
+	}
+	if len(m.Attachments) > 0 {
+		meta += " · attachments: " + html.EscapeString(strings.Join(m.Attachments, ", "))
+	}
+
+	_, err := fmt.Fprintf(t.w, "<div class=\"%s\" id=\"m%d\"><strong>%s</strong> <span class=\"meta\">%s</span><div>%s</div></div>\n",
+		class, m.ID, html.EscapeString(m.Author), meta, content)
+	return err
+}
+
+func (t *htmlTranscript) end() error {
+	_, err := io.WriteString(t.w, "</body>\n</html>\n")
+	return err
+}
+
+// textTranscript writes one line per message; thread replies are indented.
+type textTranscript struct {
+	w io.Writer
+}
+
+func (t *textTranscript) begin(room *models.Room, query models.ExportQuery) error {
+	_, err := fmt.Fprintf(t.w, "Room: %s\nExported: %s\n\n", room.Name, time.Now().Format(transcriptTimeLayout))
+	return err
+}
+
+func (t *textTranscript) message(m *models.ExportMessage) error {
+	indent := ""
+	if m.ParentID != nil {
+		indent = "    "
+	}
+
+	content := m.Content
+	if m.DeletedAt != nil {
+		content = "(message deleted)"
+	} else if m.EditedAt != nil {
+		content += " (edited)"
+	}
+	if len(m.Attachments) > 0 {
+		content += " [attachments: " + strings.Join(m.Attachments, ", ") + "]"
+	}
+	// Continuation lines of multi-line messages line up under the first one
+	content = strings.ReplaceAll(content, "\n", "\n"+indent+"    ")
+
+	_, err := fmt.Fprintf(t.w, "%s[%s] %s: %s\n", indent, m.CreatedAt.Format(transcriptTimeLayout), m.Author, content)
+	return err
+}
+
+func (t *textTranscript) end() error {
+	return nil
+}
+
+// formatOptionalTime formats a time for a transcript, or returns "" if it is not set.
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(transcriptTimeLayout)
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"bytes"
+	"chatingApp/models"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTranscript renders a room with the given messages in a format.
+func writeTranscript(t *testing.T, format string, room *models.Room, messages ...*models.ExportMessage) string {
+	t.Helper()
+	var out bytes.Buffer
+	transcript := newTranscriptWriter(format, &out)
+	if transcript == nil {
+		t.Fatalf("no writer for %q", format)
+	}
+
+	if err := transcript.begin(room, models.ExportQuery{RoomID: room.ID, Format: format}); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range messages {
+		if err := transcript.message(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := transcript.end(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+var exportCreatedAt = time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+
+func TestNewTranscriptWriterFormats(t *testing.T) {
+	for _, format := range []string{models.ExportJSON, models.ExportCSV, models.ExportHTML, models.ExportText} {
+		if newTranscriptWriter(format, &bytes.Buffer{}) == nil {
+			t.Errorf("no writer for %q", format)
+		}
+	}
+	for _, format := range []string{"", "xml", "JSON"} {
+		if newTranscriptWriter(format, &bytes.Buffer{}) != nil {
+			t.Errorf("writer for unknown format %q", format)
+		}
+	}
+}
+
+func TestCSVSafe(t *testing.T) {
+	tests := map[string]string{
+		"=HYPERLINK(\"http://evil\")": "'=HYPERLINK(\"http://evil\")",
+		"+1+1":                        "'+1+1",
+		"-2+3":                        "'-2+3",
+		"@SUM(A1:A2)":                 "'@SUM(A1:A2)",
+		"\t=1":                        "'\t=1",
+		"\r=1":                        "'\r=1",
+		"hello = world":               "hello = world",
+		"":                            "",
+		"'quoted":                     "'quoted",
+	}
+
+	for value, want := range tests {
+		if got := csvSafe(value); got != want {
+			t.Errorf("csvSafe(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
+
+func TestCSVTranscriptGuardsFormulas(t *testing.T) {
+	out := writeTranscript(t, models.ExportCSV, &models.Room{ID: 1, Name: "general"}, &models.ExportMessage{
+		ID: 7, Seq: 3, UserID: 2, Author: "=cmd|' /C calc'!A0", CreatedAt: exportCreatedAt,
+		Content: "-1+1, \"quoted\"\nsecond line", Attachments: []string{"@evil.csv", "ok.png"},
+	})
+
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("transcript is not valid CSV: %v\n%s", err, out)
+	}
+	if len(records) != 2 {
+		t.Fatalf("%d rows, want a header and one message", len(records))
+	}
+
+	row := records[1]
+	want := []string{"7", "3", "", "2", "'=cmd|' /C calc'!A0", "2024-03-01 12:30:00", "", "",
+		"'-1+1, \"quoted\"\nsecond line", "'@evil.csv; ok.png"}
+	for i := range want {
+		if row[i] != want[i] {
+			t.Errorf("column %s = %q, want %q", records[0][i], row[i], want[i])
+		}
+	}
+}
+
+func TestHTMLTranscriptEscapes(t *testing.T) {
+	deletedAt := exportCreatedAt.Add(time.Minute)
+	parentID := 1
+	out := writeTranscript(t, models.ExportHTML, &models.Room{ID: 1, Name: "</title><script>alert(1)</script>"},
+		&models.ExportMessage{ID: 1, Author: "<b>mallory</b>", CreatedAt: exportCreatedAt,
+			Content: "<img src=x onerror=alert(1)>\nline two", Attachments: []string{"\"><svg onload=alert(1)>.png"}},
+		&models.ExportMessage{ID: 2, ParentID: &parentID, Author: "bob", CreatedAt: exportCreatedAt,
+			Content: "<script>secret</script>", DeletedAt: &deletedAt},
+	)
+
+	for _, raw := range []string{"<script>", "<img", "<svg", "<b>mallory"} {
+		if strings.Contains(out, raw) {
+			t.Errorf("transcript contains unescaped %q:\n%s", raw, out)
+		}
+	}
+	for _, escaped := range []string{
+		"&lt;/title&gt;&lt;script&gt;alert(1)&lt;/script&gt;",
+		"&lt;b&gt;mallory&lt;/b&gt;",
+		"&lt;img src=x onerror=alert(1)&gt;<br>line two",
+		"&#34;&gt;&lt;svg onload=alert(1)&gt;.png",
+		`<div class="message reply" id="m2">`,
+		`<span class="deleted">message deleted</span>`,
+	} {
+		if !strings.Contains(out, escaped) {
+			t.Errorf("transcript is missing %q:\n%s", escaped, out)
+		}
+	}
+	if strings.Contains(out, "secret") {
+		t.Error("deleted message content was exported")
+	}
+}
+
+func TestTextTranscript(t *testing.T) {
+	editedAt := exportCreatedAt.Add(time.Minute)
+	parentID := 1
+	out := writeTranscript(t, models.ExportText, &models.Room{ID: 1, Name: "general"},
+		&models.ExportMessage{ID: 1, Author: "alice", CreatedAt: exportCreatedAt, Content: "first\nsecond", EditedAt: &editedAt},
+		&models.ExportMessage{ID: 2, ParentID: &parentID, Author: "bob", CreatedAt: exportCreatedAt, Content: "reply", Attachments: []string{"a.png"}},
+	)
+
+	want := "[2024-03-01 12:30:00] alice: first\n    second (edited)\n" +
+		"    [2024-03-01 12:30:00] bob: reply [attachments: a.png]\n"
+	if !strings.HasPrefix(out, "Room: general\n") || !strings.HasSuffix(out, want) {
+		t.Errorf("transcript =\n%s\nwant it to end with\n%s", out, want)
+	}
+}
+
+func TestJSONTranscriptIsValid(t *testing.T) {
+	room := &models.Room{ID: 4, Name: "\"quotes\" and </script>"}
+	for _, messages := range [][]*models.ExportMessage{
+		nil,
+		{{ID: 1, Author: "alice", Content: "hi \"there\"", CreatedAt: exportCreatedAt},
+			{ID: 2, Author: "bob", Content: "line\nbreak", CreatedAt: exportCreatedAt}},
+	} {
+		out := writeTranscript(t, models.ExportJSON, room, messages...)
+
+		var parsed struct {
+			RoomID   int                    `json:"room_id"`
+			RoomName string                 `json:"room_name"`
+			Messages []models.ExportMessage `json:"messages"`
+		}
+		if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+			t.Fatalf("transcript is not valid JSON: %v\n%s", err, out)
+		}
+		if parsed.RoomID != room.ID || parsed.RoomName != room.Name || len(parsed.Messages) != len(messages) {
+			t.Errorf("parsed %+v from\n%s", parsed, out)
+		}
+		for i, m := range messages {
+			if parsed.Messages[i].Content != m.Content {
+				t.Errorf("message %d content = %q, want %q", i, parsed.Messages[i].Content, m.Content)
+			}
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"bufio"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"chatingApp/storage"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// exportPollInterval is how often the export worker looks for jobs queued by other instances.
+	exportPollInterval = 10 * time.Second
+	// exportJobStaleAfter is how long a job may run before another worker takes it over.
+	exportJobStaleAfter = time.Hour
+)
+
+var (
+	// ErrInvalidExportFormat is returned when an export is requested in an unknown format.
+	ErrInvalidExportFormat = errors.New("format must be json, csv, html or txt")
+	// ErrExportJobNotFound is returned when an export job does not exist or belongs to someone else.
+	ErrExportJobNotFound = errors.New("export job not found")
+	// ErrExportNotReady is returned when the result of an unfinished export job is downloaded.
+	ErrExportNotReady = errors.New("export is not finished")
+)
+
+// ExportService produces room transcripts, streamed directly or built by a background worker.
+type ExportService struct {
+	ExportRepo  *repository.ExportRepository
+	RoomRepo    *repository.RoomRepository
+	Permissions *RoomPermissionService
+	Storage     storage.Storage // Keeps the results of background exports
+
+	wake     chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+	started  bool
+	stopOnce sync.Once
+}
+
+// NewExportService creates a new instance of ExportService.
+func NewExportService(exportRepo *repository.ExportRepository, roomRepo *repository.RoomRepository, permissions *RoomPermissionService, fileStorage storage.Storage) *ExportService {
+	return &ExportService{
+		ExportRepo:  exportRepo,
+		RoomRepo:    roomRepo,
+		Permissions: permissions,
+		Storage:     fileStorage,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// AuthorizeExport checks an export request before anything is written: the format must be
+// known and the caller must be a moderator or owner of the room, or a super-admin.
+func (s *ExportService) AuthorizeExport(query models.ExportQuery, userID int, role string) error {
+	if newTranscriptWriter(query.Format, io.Discard) == nil {
+		return ErrInvalidExportFormat
+	}
+
+	room, err := s.RoomRepo.GetRoomByID(query.RoomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room", err)
+		return err
+	}
+	if room == nil {
+		return ErrRoomNotFound
+	}
+	if role == "super-admin" {
+		return nil
+	}
+
+	return s.Permissions.Require(query.RoomID, userID, ActionManageRoom)
+}
+
+// WriteExport streams the transcript of a room to w. Call AuthorizeExport first.
+func (s *ExportService) WriteExport(ctx context.Context, query models.ExportQuery, w io.Writer) error {
+	room, err := s.RoomRepo.GetRoomByID(query.RoomID)
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return ErrRoomNotFound
+	}
+
+	buffered := bufio.NewWriter(w)
+	transcript := newTranscriptWriter(query.Format, buffered)
+	if transcript == nil {
+		return ErrInvalidExportFormat
+	}
+
+	if err := transcript.begin(room, query); err != nil {
+		return err
+	}
+	if err := s.ExportRepo.StreamRoomMessages(ctx, query, transcript.message); err != nil {
+		log.Println("❌ Error: Failed to export room", err)
+		return err
+	}
+	if err := transcript.end(); err != nil {
+		return err
+	}
+	return buffered.Flush()
+}
+
+// ExportFilename is the download name of a room transcript.
+func ExportFilename(roomID int, format string) string {
+	return fmt.Sprintf("room-%d-export.%s", roomID, format)
+}
+
+// CreateExportJob queues an export to be built in the background.
+func (s *ExportService) CreateExportJob(query models.ExportQuery, userID int, role string) (*models.ExportJob, error) {
+	if err := s.AuthorizeExport(query, userID, role); err != nil {
+		return nil, err
+	}
+
+	job, err := s.ExportRepo.CreateExportJob(&models.ExportJob{
+		RoomID:      query.RoomID,
+		RequestedBy: userID,
+		Format:      query.Format,
+		From:        query.From,
+		To:          query.To,
+	})
+	if err != nil {
+		log.Println("❌ Error: Failed to create export job", err)
+		return nil, err
+	}
+
+	// Start on it now rather than at the next poll
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	log.Println("✅ Export job queued for room:", query.RoomID)
+	return job, nil
+}
+
+// GetExportJob retrieves an export job for the user who requested it, or a super-admin. The
+// requester must still be allowed to export the room; a job of a room they no longer moderate
+// is reported as not found.
+func (s *ExportService) GetExportJob(jobID, userID int, role string) (*models.ExportJob, error) {
+	job, err := s.ExportRepo.GetExportJob(jobID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve export job", err)
+		return nil, err
+	}
+	if job == nil || (job.RequestedBy != userID && role != "super-admin") {
+		return nil, ErrExportJobNotFound
+	}
+
+	err = s.AuthorizeExport(models.ExportQuery{RoomID: job.RoomID, Format: job.Format}, userID, role)
+	if errors.Is(err, ErrRoomNotFound) || errors.Is(err, ErrNotRoomAdmin) {
+		return nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// OpenExportResult opens the transcript built by a finished export job. The caller closes the reader.
+func (s *ExportService) OpenExportResult(ctx context.Context, jobID, userID int, role string) (*models.ExportJob, io.ReadCloser, error) {
+	job, err := s.GetExportJob(jobID, userID, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != models.ExportDone {
+		return nil, nil, ErrExportNotReady
+	}
+
+	reader, err := s.Storage.Get(ctx, job.StorageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to read export", err)
+		return nil, nil, err
+	}
+	return job, reader, nil
+}
+
+// StartWorker builds queued exports one at a time until StopWorker is called.
+func (s *ExportService) StartWorker() {
+	s.started = true
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(exportPollInterval)
+		defer ticker.Stop()
+
+		for {
+			s.runPendingJobs()
+			select {
+			case <-ticker.C:
+			case <-s.wake:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Println("✅ Success: Export worker started")
+}
+
+// StopWorker stops the worker and waits for a running export to finish.
+func (s *ExportService) StopWorker() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started {
+			<-s.done
+		}
+	})
+}
+
+// runPendingJobs builds every queued export.
+func (s *ExportService) runPendingJobs() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		job, err := s.ExportRepo.ClaimExportJob(exportJobStaleAfter)
+		if err != nil {
+			log.Println("❌ Error: Failed to claim export job", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		if err := s.runJob(job); err != nil {
+			log.Println("❌ Error: Export job failed", job.ID, err)
+			if err := s.ExportRepo.FailExportJob(job.ID, err.Error()); err != nil {
+				log.Println("❌ Error: Failed to record export failure", err)
+			}
+			continue
+		}
+		log.Println("✅ Export job finished:", job.ID)
+	}
+}
+
+// runJob writes a job's transcript to a temporary file, so its size is known, and stores it.
+func (s *ExportService) runJob(job *models.ExportJob) error {
+	file, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	ctx := context.Background()
+	query := models.ExportQuery{RoomID: job.RoomID, Format: job.Format, From: job.From, To: job.To}
+	if err := s.WriteExport(ctx, query, file); err != nil {
+		return err
+	}
+
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("exports/%d/%d-%s.%s", job.RoomID, job.ID, randomKey(), job.Format)
+	if err := s.Storage.Put(ctx, key, file, size, ExportContentType(job.Format)); err != nil {
+		return err
+	}
+	return s.ExportRepo.FinishExportJob(job.ID, key, size)
+}
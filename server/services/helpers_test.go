@@ -0,0 +1,102 @@
+package services
+
+import (
+	"chatingApp/config"
+	"chatingApp/dbtest"
+	"chatingApp/hub"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"database/sql"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testEnv wires the services over a database of their own.
+type testEnv struct {
+	db          *sql.DB
+	hub         *hub.Hub
+	roomRepo    *repository.RoomRepository
+	permissions *RoomPermissionService
+	rooms       *RoomService
+}
+
+// newTestEnv opens a test database and builds the services on it, with the default config.
+// It skips the test when no database is configured.
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	db, _ := dbtest.Open(t)
+
+	if config.AppConfig == nil {
+		config.LoadConfig()
+	}
+
+	chatHub, err := hub.NewHub(hub.Config{
+		PingInterval:   time.Minute,
+		PongWait:       2 * time.Minute,
+		WriteWait:      time.Second,
+		MaxMessageSize: 8192,
+	}, hub.NewMemoryBroadcaster(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	roomRepo := repository.NewRoomRepository(db)
+	permissions := NewRoomPermissionService(roomRepo)
+	return &testEnv{
+		db:          db,
+		hub:         chatHub,
+		roomRepo:    roomRepo,
+		permissions: permissions,
+		rooms:       NewRoomService(roomRepo, repository.NewAttachmentRepository(db), permissions, chatHub),
+	}
+}
+
+var testUserCount atomic.Int64
+
+// createUser adds a user account and returns its ID.
+func (e *testEnv) createUser(t *testing.T) int {
+	t.Helper()
+	n := testUserCount.Add(1)
+	var id int
+	err := e.db.QueryRow(`INSERT INTO users (name, email, password, role) VALUES ($1, $2, 'x', 'user') RETURNING id;`,
+		fmt.Sprintf("user%d", n), fmt.Sprintf("user%d@example.com", n)).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// createRoom creates a room owned by ownerID, with the given members as plain members.
+func (e *testEnv) createRoom(t *testing.T, ownerID int, members ...int) int {
+	t.Helper()
+	room, err := e.rooms.CreateRoom(&models.Room{Name: "room", CreatedBy: ownerID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, userID := range members {
+		if _, err := e.roomRepo.AddUserToRoom(room.ID, userID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return room.ID
+}
+
+// setRole changes a member's role directly in the database.
+func (e *testEnv) setRole(t *testing.T, roomID, userID int, role string) {
+	t.Helper()
+	if err := e.roomRepo.SetMemberRole(roomID, userID, role); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// role returns a user's role in a room, or "" if they are not in it.
+func (e *testEnv) role(t *testing.T, roomID, userID int) string {
+	t.Helper()
+	role, err := e.roomRepo.GetMemberRole(roomID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return role
+}
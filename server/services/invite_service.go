@@ -0,0 +1,195 @@
+package services
+
+import (
+	"chatingApp/config"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"log"
+	"time"
+)
+
+var (
+	// ErrInviteNotFound is returned when an invite does not exist or is meant for another user.
+	ErrInviteNotFound = errors.New("invite not found")
+	// ErrInviteUnavailable is returned when an invite was revoked, has expired or is used up.
+	ErrInviteUnavailable = errors.New("invite is no longer valid")
+	// ErrInvalidInviteExpiry is returned when an invite would expire in the past or beyond INVITE_MAX_TTL.
+	ErrInvalidInviteExpiry = errors.New("invite expiry must be in the future and within INVITE_MAX_TTL")
+)
+
+// InviteService manages invites that let users join rooms.
+type InviteService struct {
+	InviteRepo  *repository.InviteRepository
+	RoomRepo    *repository.RoomRepository
+	Permissions *RoomPermissionService
+}
+
+// NewInviteService creates a new instance of InviteService.
+func NewInviteService(inviteRepo *repository.InviteRepository, roomRepo *repository.RoomRepository, permissions *RoomPermissionService) *InviteService {
+	return &InviteService{InviteRepo: inviteRepo, RoomRepo: roomRepo, Permissions: permissions}
+}
+
+// CreateInvite creates an invite to a room. Only moderators and the owner may invite.
+func (s *InviteService) CreateInvite(roomID, requesterID int, request models.InviteCreateRequest) (*models.RoomInvite, error) {
+	now := time.Now()
+	expiresAt := now.Add(config.AppConfig.InviteDefaultTTL)
+	if request.ExpiresAt != nil {
+		expiresAt = *request.ExpiresAt
+	}
+	if !expiresAt.After(now) || expiresAt.After(now.Add(config.AppConfig.InviteMaxTTL)) {
+		return nil, ErrInvalidInviteExpiry
+	}
+
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	invite, err := s.InviteRepo.CreateInvite(&models.RoomInvite{
+		RoomID:       roomID,
+		Code:         inviteCode(),
+		CreatedBy:    requesterID,
+		TargetUserID: request.UserID,
+		MaxUses:      request.MaxUses,
+		ExpiresAt:    expiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		log.Println("❌ Error: Failed to create invite", err)
+		return nil, err
+	}
+
+	log.Println("✅ Invite created successfully:", roomID, invite.ID)
+	return invite, nil
+}
+
+// GetRoomInvites retrieves the invites of a room, newest first; activeOnly keeps only those that
+// can still be accepted. Only moderators and the owner may list them.
+func (s *InviteService) GetRoomInvites(roomID, requesterID int, activeOnly bool) ([]models.RoomInvite, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.InviteRepo.GetRoomInvites(roomID, activeOnly)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve invites", err)
+		return nil, err
+	}
+	return invites, nil
+}
+
+// RevokeInvite revokes an invite of a room. Only moderators and the owner may revoke invites.
+func (s *InviteService) RevokeInvite(roomID, inviteID, requesterID int) (*models.RoomInvite, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	invite, err := s.InviteRepo.RevokeInvite(roomID, inviteID)
+	if err != nil {
+		log.Println("❌ Error: Failed to revoke invite", err)
+		return nil, err
+	}
+	if invite == nil {
+		return nil, ErrInviteNotFound
+	}
+
+	log.Println("✅ Invite revoked successfully:", roomID, inviteID)
+	return invite, nil
+}
+
+// GetInviteUses retrieves who joined a room through one of its invites. Only moderators and
+// the owner may see them.
+func (s *InviteService) GetInviteUses(roomID, inviteID, requesterID int) ([]models.InviteUse, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	invite, err := s.InviteRepo.GetInvite(roomID, inviteID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve invite", err)
+		return nil, err
+	}
+	if invite == nil {
+		return nil, ErrInviteNotFound
+	}
+
+	uses, err := s.InviteRepo.GetInviteUses(inviteID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve invite uses", err)
+		return nil, err
+	}
+	return uses, nil
+}
+
+// PreviewInvite shows a user which room an invite is for before they accept it.
+// Invites meant for someone else read as not found.
+func (s *InviteService) PreviewInvite(code string, userID int) (*models.InvitePreview, error) {
+	invite, err := s.InviteRepo.GetInviteByCode(code)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve invite", err)
+		return nil, err
+	}
+	if invite == nil || (invite.TargetUserID != nil && *invite.TargetUserID != userID) {
+		return nil, ErrInviteNotFound
+	}
+	if invite.Status != models.InviteActive {
+		return nil, ErrInviteUnavailable
+	}
+
+	room, err := s.RoomRepo.GetRoomByID(invite.RoomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room", err)
+		return nil, err
+	}
+	if room == nil {
+		return nil, ErrInviteNotFound
+	}
+
+	return &models.InvitePreview{
+		Code:            invite.Code,
+		RoomID:          room.ID,
+		RoomName:        room.Name,
+		RoomDescription: room.Description,
+		Member:          s.Permissions.IsMember(room.ID, userID),
+		ExpiresAt:       invite.ExpiresAt,
+	}, nil
+}
+
+// AcceptInvite adds the user to the room of an invite and returns the room. The returned bool
+// reports whether they joined; accepting an invite to a room one is already in changes nothing.
+func (s *InviteService) AcceptInvite(code string, userID int) (*models.Room, bool, error) {
+	invite, joined, err := s.InviteRepo.AcceptInvite(code, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrInviteNotFound):
+			return nil, false, ErrInviteNotFound
+		case errors.Is(err, repository.ErrInviteUnavailable):
+			return nil, false, ErrInviteUnavailable
+		}
+		log.Println("❌ Error: Failed to accept invite", err)
+		return nil, false, err
+	}
+
+	room, err := s.RoomRepo.GetRoomByID(invite.RoomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room", err)
+		return nil, false, err
+	}
+	if joined {
+		log.Println("✅ User joined room through invite:", invite.RoomID, invite.ID, userID)
+	}
+	return room, joined, nil
+}
+
+// inviteCode returns a new random, URL-safe invite code.
+func inviteCode() string {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		log.Fatal("❌ Error: Failed to generate invite code:", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
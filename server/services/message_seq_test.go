@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentMessagesGetConsecutiveSeqs(t *testing.T) {
+	env := newTestEnv(t)
+	owner, member := env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, member)
+	otherRoomID := env.createRoom(t, owner)
+
+	const senders, perSender = 8, 10
+	seqs := make(chan int64, senders*perSender)
+	var wg sync.WaitGroup
+	for i := 0; i < senders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			userID := owner
+			if i%2 == 1 {
+				userID = member
+			}
+			for j := 0; j < perSender; j++ {
+				message, err := env.rooms.AddMessageToRoom(roomID, userID, fmt.Sprintf("message %d.%d", i, j), 0, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				seqs <- message.Seq
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(seqs)
+
+	// Every number from 1 up is used exactly once
+	seen := make(map[int64]bool)
+	for seq := range seqs {
+		if seen[seq] {
+			t.Errorf("seq %d used twice", seq)
+		}
+		seen[seq] = true
+	}
+	for seq := int64(1); seq <= senders*perSender; seq++ {
+		if !seen[seq] {
+			t.Errorf("seq %d skipped", seq)
+		}
+	}
+
+	latest, err := env.roomRepo.GetRoomLastSeq(roomID)
+	if err != nil || latest != senders*perSender {
+		t.Errorf("room last_seq = %d (%v), want %d", latest, err, senders*perSender)
+	}
+
+	// Each room counts on its own
+	message, err := env.rooms.AddMessageToRoom(otherRoomID, owner, "elsewhere", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Seq != 1 {
+		t.Errorf("first message of another room has seq %d, want 1", message.Seq)
+	}
+}
+
+func TestGetMessagesSinceSeq(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser(t)
+	roomID := env.createRoom(t, owner)
+
+	for i := 1; i <= 5; i++ {
+		message, err := env.rooms.AddMessageToRoom(roomID, owner, fmt.Sprintf("message %d", i), 0, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i == 4 {
+			if err := env.rooms.AddReaction(roomID, message.ID, owner, "👍"); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	messages, latest, err := env.rooms.GetMessagesSinceSeq(roomID, owner, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if latest != 5 || len(messages) != 3 {
+		t.Fatalf("got %d messages up to seq %d, want 3 up to 5", len(messages), latest)
+	}
+	for i, message := range messages {
+		if message.Seq != int64(i+3) || message.Content != fmt.Sprintf("message %d", i+3) {
+			t.Errorf("message %d has seq %d and content %q", i, message.Seq, message.Content)
+		}
+	}
+	// Replayed messages carry their reactions like listed ones
+	if reactions := messages[1].Reactions; len(reactions) != 1 || reactions[0].Emoji != "👍" || !reactions[0].Reacted {
+		t.Errorf("replayed message has reactions %+v, want the requester's 👍", reactions)
+	}
+
+	messages, latest, err = env.rooms.GetMessagesSinceSeq(roomID, owner, 5, 10)
+	if err != nil || latest != 5 || len(messages) != 0 {
+		t.Errorf("up to date client got %d messages up to seq %d (%v), want none", len(messages), latest, err)
+	}
+
+	// A client too far behind reloads history instead
+	_, latest, err = env.rooms.GetMessagesSinceSeq(roomID, owner, 1, 3)
+	if !errors.Is(err, ErrResumeGapTooLarge) || latest != 5 {
+		t.Errorf("got seq %d and %v, want 5 and ErrResumeGapTooLarge", latest, err)
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"chatingApp/config"
+	"chatingApp/models"
+	"chatingApp/repository"
+	"chatingApp/storage"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// maxRetentionDays bounds a room's retention period.
+const maxRetentionDays = 36500
+
+var (
+	// ErrInvalidRetention is returned when a retention policy has a bad period or action.
+	ErrInvalidRetention = errors.New("retention_days must be between 0 and 36500 and action delete or archive")
+	// ErrRoomNotFound is returned when a room does not exist.
+	ErrRoomNotFound = errors.New("room not found")
+)
+
+// RetentionService manages message retention policies and runs the background purger
+// that removes messages and system logs past them, and expired export results.
+type RetentionService struct {
+	RetentionRepo *repository.RetentionRepository
+	Permissions   *RoomPermissionService
+	Storage       storage.Storage // Holds the files of purged attachments
+
+	stop     chan struct{}
+	done     chan struct{}
+	started  bool
+	stopOnce sync.Once
+}
+
+// NewRetentionService creates a new instance of RetentionService.
+func NewRetentionService(retentionRepo *repository.RetentionRepository, permissions *RoomPermissionService, fileStorage storage.Storage) *RetentionService {
+	return &RetentionService{
+		RetentionRepo: retentionRepo,
+		Permissions:   permissions,
+		Storage:       fileStorage,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// GetRoomRetention retrieves the retention policy in effect for a room. Only moderators and
+// the owner may view it.
+func (s *RetentionService) GetRoomRetention(roomID, userID int) (*models.RetentionPolicy, error) {
+	if err := s.Permissions.Require(roomID, userID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	days, action, found, err := s.RetentionRepo.GetRoomRetention(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve retention policy", err)
+		return nil, err
+	}
+	if !found {
+		return nil, ErrRoomNotFound
+	}
+	return effectiveRetention(roomID, days, action), nil
+}
+
+// SetRoomRetention sets a room's retention policy; nil values fall back to the server-wide
+// default. Only moderators and the owner may change it.
+func (s *RetentionService) SetRoomRetention(roomID, userID int, days *int, action *string) (*models.RetentionPolicy, error) {
+	if days != nil && (*days < 0 || *days > maxRetentionDays) {
+		return nil, ErrInvalidRetention
+	}
+	if action != nil && *action != models.RetentionDelete && *action != models.RetentionArchive {
+		return nil, ErrInvalidRetention
+	}
+	if err := s.Permissions.Require(roomID, userID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	if err := s.RetentionRepo.SetRoomRetention(roomID, days, action); err != nil {
+		log.Println("❌ Error: Failed to set retention policy", err)
+		return nil, err
+	}
+	log.Println("✅ Retention policy updated for room:", roomID)
+	return effectiveRetention(roomID, days, action), nil
+}
+
+// GetPurgeRuns retrieves the latest purge runs, newest first.
+func (s *RetentionService) GetPurgeRuns(limit int) ([]models.PurgeRun, error) {
+	runs, err := s.RetentionRepo.GetPurgeRuns(clampPageSize(limit))
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve purge runs", err)
+		return nil, err
+	}
+	return runs, nil
+}
+
+// effectiveRetention fills in the server-wide default for the settings a room leaves unset.
+func effectiveRetention(roomID int, days *int, action *string) *models.RetentionPolicy {
+	policy := &models.RetentionPolicy{
+		RoomID:        roomID,
+		RetentionDays: config.AppConfig.MessageRetentionDays,
+		Action:        config.AppConfig.MessageRetentionAction,
+		Inherited:     days == nil,
+	}
+	if days != nil {
+		policy.RetentionDays = *days
+	}
+	if action != nil {
+		policy.Action = *action
+	}
+	return policy
+}
+
+// StartPurger removes expired messages and system logs every interval, starting at once,
+// until StopPurger is called.
+func (s *RetentionService) StartPurger(interval time.Duration) {
+	s.started = true
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.purge()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Println("✅ Success: Retention purger started")
+}
+
+// StopPurger stops the purger and waits for a running batch to finish.
+func (s *RetentionService) StopPurger() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started {
+			<-s.done
+		}
+	})
+}
+
+// purge runs one pass over every room with a retention period and over the system logs,
+// and records how many rows it removed.
+func (s *RetentionService) purge() {
+	run := &models.PurgeRun{StartedAt: time.Now()}
+	err := s.purgeMessages(run)
+	if err == nil {
+		err = s.purgeSystemLogs(run)
+	}
+	if err == nil {
+		err = s.purgeExports(run)
+	}
+	if err == nil {
+		err = s.purgeUploads(run)
+	}
+	if err != nil {
+		log.Println("❌ Error: Purge run failed", err)
+		run.Error = err.Error()
+	}
+
+	run.FinishedAt = time.Now()
+	if err := s.RetentionRepo.RecordPurgeRun(run); err != nil {
+		log.Println("❌ Error: Failed to record purge run", err)
+		return
+	}
+	log.Printf("🧹 Purge run %d removed %d messages, %d system logs, %d exports and %d unsent uploads\n",
+		run.ID, run.MessagesDeleted+run.MessagesArchived, run.SystemLogsDeleted, run.ExportsExpired, run.UploadsExpired)
+}
+
+// purgeMessages removes expired threads from every room in batches.
+func (s *RetentionService) purgeMessages(run *models.PurgeRun) error {
+	policies, err := s.RetentionRepo.GetExpiringRooms(config.AppConfig.MessageRetentionDays, config.AppConfig.MessageRetentionAction)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		archive := policy.Action == models.RetentionArchive
+		for more := true; more; {
+			if s.stopping() {
+				return nil
+			}
+
+			var removed int
+			var keys []string
+			removed, keys, more, err = s.RetentionRepo.PurgeRoomMessages(policy.RoomID, policy.RetentionDays, archive, config.AppConfig.PurgeBatchSize)
+			if err != nil {
+				return err
+			}
+			if archive {
+				run.MessagesArchived += removed
+			} else {
+				run.MessagesDeleted += removed
+			}
+			s.deleteFiles(keys)
+		}
+	}
+	return nil
+}
+
+// purgeSystemLogs deletes expired system logs in batches.
+func (s *RetentionService) purgeSystemLogs(run *models.PurgeRun) error {
+	days := config.AppConfig.SystemLogRetentionDays
+	if days <= 0 {
+		return nil
+	}
+
+	for {
+		if s.stopping() {
+			return nil
+		}
+		deleted, err := s.RetentionRepo.PurgeSystemLogs(days, config.AppConfig.PurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		run.SystemLogsDeleted += deleted
+		if deleted < config.AppConfig.PurgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// purgeExports deletes export jobs finished longer than EXPORT_RESULT_TTL ago, with their results.
+func (s *RetentionService) purgeExports(run *models.PurgeRun) error {
+	ttl := config.AppConfig.ExportResultTTL
+	if ttl <= 0 {
+		return nil
+	}
+
+	for {
+		if s.stopping() {
+			return nil
+		}
+		deleted, keys, err := s.RetentionRepo.PurgeExportJobs(ttl, config.AppConfig.PurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		run.ExportsExpired += deleted
+		s.deleteFiles(keys)
+		if deleted < config.AppConfig.PurgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// purgeUploads deletes attachments never sent with a message within ATTACHMENT_UNSENT_TTL of
+// their upload, with their files.
+func (s *RetentionService) purgeUploads(run *models.PurgeRun) error {
+	ttl := config.AppConfig.AttachmentUnsentTTL
+	if ttl <= 0 {
+		return nil
+	}
+
+	for {
+		if s.stopping() {
+			return nil
+		}
+		deleted, keys, err := s.RetentionRepo.PurgeUnsentAttachments(ttl, config.AppConfig.PurgeBatchSize)
+		if err != nil {
+			return err
+		}
+		run.UploadsExpired += deleted
+		s.deleteFiles(keys)
+		if deleted < config.AppConfig.PurgeBatchSize {
+			return nil
+		}
+	}
+}
+
+// deleteFiles removes the stored files of purged attachments and exports. A file that cannot
+// be deleted is only logged; its row is already gone.
+func (s *RetentionService) deleteFiles(keys []string) {
+	for _, key := range keys {
+		if err := s.Storage.Delete(context.Background(), key); err != nil {
+			log.Println("❌ Error: Failed to delete purged file", key, err)
+		}
+	}
+}
+
+// stopping reports whether StopPurger has been called.
+func (s *RetentionService) stopping() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
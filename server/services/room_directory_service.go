@@ -0,0 +1,164 @@
+package services
+
+import (
+	"chatingApp/models"
+	"chatingApp/repository"
+	"errors"
+	"log"
+	"strings"
+)
+
+// Room directory paging limits.
+const (
+	DefaultDirectoryPageSize = 20
+	MaxDirectoryPageSize     = 100
+)
+
+var (
+	// ErrRoomInviteOnly is returned when a user tries to join a private room without an invite.
+	ErrRoomInviteOnly = errors.New("room is invite only")
+	// ErrJoinRequestNotFound is returned when a join request does not exist in the given room.
+	ErrJoinRequestNotFound = errors.New("join request not found")
+	// ErrJoinRequestDecided is returned when a join request was already approved, denied or cancelled.
+	ErrJoinRequestDecided = errors.New("join request is no longer pending")
+	// ErrInvalidJoinRequestStatus is returned when join requests are filtered by an unknown status.
+	ErrInvalidJoinRequestStatus = errors.New("status must be pending, approved, denied or cancelled")
+)
+
+// RoomDirectoryService lists the rooms users can find and lets them join public rooms or ask
+// to join request rooms.
+type RoomDirectoryService struct {
+	RoomRepo        *repository.RoomRepository
+	JoinRequestRepo *repository.JoinRequestRepository
+	Permissions     *RoomPermissionService
+}
+
+// NewRoomDirectoryService creates a new instance of RoomDirectoryService.
+func NewRoomDirectoryService(roomRepo *repository.RoomRepository, joinRequestRepo *repository.JoinRequestRepository, permissions *RoomPermissionService) *RoomDirectoryService {
+	return &RoomDirectoryService{RoomRepo: roomRepo, JoinRequestRepo: joinRequestRepo, Permissions: permissions}
+}
+
+// GetDirectory retrieves a page of the rooms the caller can see, by name: public and request
+// rooms, and private rooms they are in. Super-admins see every room.
+func (s *RoomDirectoryService) GetDirectory(query models.RoomDirectoryQuery, callerID int, callerRole string) (*models.RoomDirectoryResponse, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Limit <= 0 {
+		query.Limit = DefaultDirectoryPageSize
+	}
+	if query.Limit > MaxDirectoryPageSize {
+		query.Limit = MaxDirectoryPageSize
+	}
+	limit := query.Limit
+
+	// Fetch one extra row to find out whether another page exists
+	query.Limit++
+	rooms, err := s.RoomRepo.GetRoomDirectory(callerID, query, callerRole == "super-admin")
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room directory", err)
+		return nil, err
+	}
+
+	hasMore := len(rooms) > limit
+	if hasMore {
+		rooms = rooms[:limit]
+	}
+	return &models.RoomDirectoryResponse{Rooms: rooms, HasMore: hasMore}, nil
+}
+
+// JoinRoom joins the user to a public room, or files a join request for a request room that
+// its moderators can approve. Private rooms can only be joined through an invite. Joining a
+// room one is already in changes nothing.
+func (s *RoomDirectoryService) JoinRoom(roomID, userID int, message string) (*models.JoinRoomResponse, error) {
+	room, err := s.RoomRepo.GetRoomByID(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room", err)
+		return nil, err
+	}
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+	if s.Permissions.IsMember(roomID, userID) {
+		return &models.JoinRoomResponse{Joined: true, Room: room}, nil
+	}
+
+	switch room.Visibility {
+	case models.RoomPublic:
+		if _, err := s.RoomRepo.AddUserToRoom(roomID, userID); err != nil {
+			log.Println("❌ Error: Failed to add user to room", err)
+			return nil, roomMembershipError(err)
+		}
+		log.Println("✅ User joined public room:", roomID, userID)
+		return &models.JoinRoomResponse{Joined: true, Room: room}, nil
+
+	case models.RoomRequest:
+		request, created, err := s.JoinRequestRepo.CreateJoinRequest(roomID, userID, strings.TrimSpace(message))
+		if err != nil {
+			log.Println("❌ Error: Failed to create join request", err)
+			return nil, err
+		}
+		if created {
+			log.Println("✅ Join request created successfully:", roomID, request.ID)
+		}
+		return &models.JoinRoomResponse{Joined: false, JoinRequest: request}, nil
+	}
+
+	log.Println("❌ Error: Room is invite only")
+	return nil, ErrRoomInviteOnly
+}
+
+// CancelJoinRequest withdraws the user's pending request to join a room.
+func (s *RoomDirectoryService) CancelJoinRequest(roomID, userID int) (*models.JoinRequest, error) {
+	request, err := s.JoinRequestRepo.CancelJoinRequest(roomID, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to cancel join request", err)
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrJoinRequestNotFound
+	}
+	return request, nil
+}
+
+// GetJoinRequests retrieves the join requests of a room, filtered by status. Only moderators
+// and the owner may list them.
+func (s *RoomDirectoryService) GetJoinRequests(roomID, requesterID int, status string) ([]models.JoinRequest, error) {
+	switch status {
+	case "", models.JoinRequestPending, models.JoinRequestApproved, models.JoinRequestDenied, models.JoinRequestCancelled:
+	default:
+		return nil, ErrInvalidJoinRequestStatus
+	}
+
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.JoinRequestRepo.GetJoinRequests(roomID, status)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve join requests", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+// DecideJoinRequest approves a pending join request, adding the user to the room, or denies it.
+// Only moderators and the owner may decide.
+func (s *RoomDirectoryService) DecideJoinRequest(roomID, requestID, requesterID int, approve bool) (*models.JoinRequest, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	request, err := s.JoinRequestRepo.DecideJoinRequest(roomID, requestID, requesterID, approve)
+	if err != nil {
+		if errors.Is(err, repository.ErrJoinRequestDecided) {
+			return nil, ErrJoinRequestDecided
+		}
+		log.Println("❌ Error: Failed to decide join request", err)
+		return nil, err
+	}
+	if request == nil {
+		return nil, ErrJoinRequestNotFound
+	}
+
+	log.Println("✅ Join request decided successfully:", roomID, requestID, request.Status)
+	return request, nil
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"chatingApp/models"
+	"chatingApp/repository"
+	"log"
+)
+
+// RoomAction is something a user may want to do in a room. Each action needs a minimum room role.
+type RoomAction int
+
+const (
+	// ActionParticipate covers reading and posting messages, reacting and uploading files.
+	ActionParticipate RoomAction = iota
+	// ActionModerate covers deleting other members' messages, reading edit history and pinning.
+	ActionModerate
+	// ActionManageRoom covers changing room details and members, retention and exports.
+	ActionManageRoom
+	// ActionManageRoles covers promoting members to moderator and demoting them again.
+	ActionManageRoles
+	// ActionDeleteRoom covers deleting the room.
+	ActionDeleteRoom
+	// ActionTransferOwnership covers handing the room to another member.
+	ActionTransferOwnership
+)
+
+// roomRoleRank orders room roles; a higher rank includes the rights of every lower one.
+var roomRoleRank = map[string]int{
+	models.RoomRoleMember:    1,
+	models.RoomRoleModerator: 2,
+	models.RoomRoleOwner:     3,
+}
+
+// actionMinRole is the least privileged room role allowed to perform each action.
+var actionMinRole = map[RoomAction]string{
+	ActionParticipate:       models.RoomRoleMember,
+	ActionModerate:          models.RoomRoleModerator,
+	ActionManageRoom:        models.RoomRoleModerator,
+	ActionManageRoles:       models.RoomRoleOwner,
+	ActionDeleteRoom:        models.RoomRoleOwner,
+	ActionTransferOwnership: models.RoomRoleOwner,
+}
+
+// RoomPermissionService decides what users may do in rooms, based on their room role.
+// Every room membership and role check goes through it.
+type RoomPermissionService struct {
+	RoomRepo *repository.RoomRepository
+}
+
+// NewRoomPermissionService creates a new instance of RoomPermissionService.
+func NewRoomPermissionService(roomRepo *repository.RoomRepository) *RoomPermissionService {
+	return &RoomPermissionService{RoomRepo: roomRepo}
+}
+
+// Role returns the user's role in the room, or "" if they are not a member.
+func (p *RoomPermissionService) Role(roomID, userID int) (string, error) {
+	role, err := p.RoomRepo.GetMemberRole(roomID, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to check room role", err)
+		return "", err
+	}
+	return role, nil
+}
+
+// IsMember reports whether the user is a member of the room.
+func (p *RoomPermissionService) IsMember(roomID, userID int) bool {
+	return p.RoomRepo.IsUserInRoom(roomID, userID)
+}
+
+// Can reports whether the user's role in the room allows the action.
+func (p *RoomPermissionService) Can(roomID, userID int, action RoomAction) (bool, error) {
+	role, err := p.Role(roomID, userID)
+	if err != nil {
+		return false, err
+	}
+	return RoleAllows(role, action), nil
+}
+
+// Require returns nil if the user may perform the action in the room. Otherwise it returns
+// ErrUserNotInRoom for ActionParticipate, ErrNotRoomOwner for owner-only actions and
+// ErrNotRoomAdmin for the rest.
+func (p *RoomPermissionService) Require(roomID, userID int, action RoomAction) error {
+	allowed, err := p.Can(roomID, userID, action)
+	if err != nil {
+		return err
+	}
+	if allowed {
+		return nil
+	}
+
+	switch actionMinRole[action] {
+	case models.RoomRoleMember:
+		log.Println("❌ Error: User is not in the room")
+		return ErrUserNotInRoom
+	case models.RoomRoleOwner:
+		log.Println("❌ Error: User is not the owner of the room")
+		return ErrNotRoomOwner
+	}
+	log.Println("❌ Error: User is not a moderator of the room")
+	return ErrNotRoomAdmin
+}
+
+// RoleAllows reports whether a room role is enough for the action. "" is no role at all.
+func RoleAllows(role string, action RoomAction) bool {
+	return role != "" && roomRoleRank[role] >= roomRoleRank[actionMinRole[action]]
+}
+
+// Outranks reports whether a member with actorRole may remove a member with targetRole:
+// moderators can remove members, and the owner anyone but themselves.
+func Outranks(actorRole, targetRole string) bool {
+	return RoleAllows(actorRole, ActionManageRoom) && roomRoleRank[actorRole] > roomRoleRank[targetRole]
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"chatingApp/models"
+	"testing"
+)
+
+func TestRoleAllows(t *testing.T) {
+	actions := []RoomAction{
+		ActionParticipate, ActionModerate, ActionManageRoom,
+		ActionManageRoles, ActionDeleteRoom, ActionTransferOwnership,
+	}
+	// The actions each role may perform, in the order above
+	tests := map[string][]bool{
+		"":                       {false, false, false, false, false, false},
+		models.RoomRoleMember:    {true, false, false, false, false, false},
+		models.RoomRoleModerator: {true, true, true, false, false, false},
+		models.RoomRoleOwner:     {true, true, true, true, true, true},
+		"unknown":                {false, false, false, false, false, false},
+	}
+
+	for role, want := range tests {
+		for i, action := range actions {
+			if got := RoleAllows(role, action); got != want[i] {
+				t.Errorf("RoleAllows(%q, %d) = %v, want %v", role, action, got, want[i])
+			}
+		}
+	}
+}
+
+func TestEveryActionHasMinimumRole(t *testing.T) {
+	for action := ActionParticipate; action <= ActionTransferOwnership; action++ {
+		if _, ok := roomRoleRank[actionMinRole[action]]; !ok {
+			t.Errorf("action %d has no minimum room role", action)
+		}
+	}
+}
+
+func TestOutranks(t *testing.T) {
+	tests := []struct {
+		actor, target string
+		want          bool
+	}{
+		{models.RoomRoleOwner, models.RoomRoleModerator, true},
+		{models.RoomRoleOwner, models.RoomRoleMember, true},
+		{models.RoomRoleOwner, models.RoomRoleOwner, false},
+		{models.RoomRoleModerator, models.RoomRoleMember, true},
+		{models.RoomRoleModerator, models.RoomRoleModerator, false},
+		{models.RoomRoleModerator, models.RoomRoleOwner, false},
+		{models.RoomRoleMember, models.RoomRoleMember, false},
+		{models.RoomRoleMember, "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		if got := Outranks(tt.actor, tt.target); got != tt.want {
+			t.Errorf("Outranks(%q, %q) = %v, want %v", tt.actor, tt.target, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"chatingApp/models"
+	"errors"
+	"testing"
+)
+
+func TestRemoveUserFromRoomRespectsRanks(t *testing.T) {
+	env := newTestEnv(t)
+	owner, mod, mod2, member, member2 := env.createUser(t), env.createUser(t), env.createUser(t), env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, mod, mod2, member, member2)
+	env.setRole(t, roomID, mod, models.RoomRoleModerator)
+	env.setRole(t, roomID, mod2, models.RoomRoleModerator)
+
+	tests := []struct {
+		name      string
+		requester int
+		target    int
+		want      error
+	}{
+		{"member cannot remove a member", member, member2, ErrNotRoomAdmin},
+		{"moderator cannot remove a moderator", mod, mod2, ErrMemberOutranks},
+		{"moderator cannot remove the owner", mod, owner, ErrRoomOwnerRemoval},
+		{"owner cannot leave", owner, owner, ErrRoomOwnerRemoval},
+		{"moderator removes a member", mod, member, nil},
+		{"member leaves", member2, member2, nil},
+		{"owner removes a moderator", owner, mod2, nil},
+		{"nobody removes a non-member", owner, member, ErrMemberNotFound},
+	}
+
+	for _, tt := range tests {
+		err := env.rooms.RemoveUserFromRoom(roomID, tt.target, tt.requester)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	for userID, want := range map[int]string{owner: "owner", mod: "moderator", mod2: "", member: "", member2: ""} {
+		if got := env.role(t, roomID, userID); got != want {
+			t.Errorf("user %d has role %q, want %q", userID, got, want)
+		}
+	}
+}
+
+func TestReplacingMembersRespectsRanks(t *testing.T) {
+	env := newTestEnv(t)
+	owner, mod, mod2, member := env.createUser(t), env.createUser(t), env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, mod, mod2, member)
+	env.setRole(t, roomID, mod, models.RoomRoleModerator)
+	env.setRole(t, roomID, mod2, models.RoomRoleModerator)
+
+	// A moderator may not drop another moderator from the list
+	users := []int{owner, mod, member}
+	_, err := env.rooms.UpdateRoomDetails(roomID, mod, models.RoomUpdateRequest{Users: &users})
+	if !errors.Is(err, ErrMemberOutranks) {
+		t.Errorf("moderator dropping a moderator: got %v, want ErrMemberOutranks", err)
+	}
+	if got := env.role(t, roomID, mod2); got != models.RoomRoleModerator {
+		t.Errorf("moderator was removed anyway: role %q", got)
+	}
+
+	// but may drop members, and leave themselves out
+	users = []int{owner, mod2}
+	if _, err := env.rooms.UpdateRoomDetails(roomID, mod, models.RoomUpdateRequest{Users: &users}); err != nil {
+		t.Fatalf("moderator dropping a member: %v", err)
+	}
+	for userID, want := range map[int]string{owner: "owner", mod: "", mod2: "moderator", member: ""} {
+		if got := env.role(t, roomID, userID); got != want {
+			t.Errorf("user %d has role %q, want %q", userID, got, want)
+		}
+	}
+}
+
+func TestSetMemberRole(t *testing.T) {
+	env := newTestEnv(t)
+	owner, mod, member := env.createUser(t), env.createUser(t), env.createUser(t)
+	outsider := env.createUser(t)
+	roomID := env.createRoom(t, owner, mod, member)
+	env.setRole(t, roomID, mod, models.RoomRoleModerator)
+
+	tests := []struct {
+		name      string
+		requester int
+		target    int
+		role      string
+		want      error
+	}{
+		{"only the owner changes roles", mod, member, models.RoomRoleModerator, ErrNotRoomOwner},
+		{"members cannot promote themselves", member, member, models.RoomRoleModerator, ErrNotRoomOwner},
+		{"ownership is not a role to set", owner, member, models.RoomRoleOwner, ErrInvalidRoomRole},
+		{"the owner keeps their role", owner, owner, models.RoomRoleMember, ErrRoomOwnerRemoval},
+		{"outsiders have no role", owner, outsider, models.RoomRoleModerator, ErrMemberNotFound},
+		{"owner demotes a moderator", owner, mod, models.RoomRoleMember, nil},
+		{"owner promotes a member", owner, member, models.RoomRoleModerator, nil},
+	}
+
+	for _, tt := range tests {
+		_, err := env.rooms.SetMemberRole(roomID, tt.target, tt.requester, tt.role)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, err, tt.want)
+		}
+	}
+
+	for userID, want := range map[int]string{owner: "owner", mod: "member", member: "moderator", outsider: ""} {
+		if got := env.role(t, roomID, userID); got != want {
+			t.Errorf("user %d has role %q, want %q", userID, got, want)
+		}
+	}
+
+	// A demoted moderator loses the right to remove members
+	if err := env.rooms.RemoveUserFromRoom(roomID, member, mod); !errors.Is(err, ErrNotRoomAdmin) {
+		t.Errorf("demoted moderator removing a member: got %v, want ErrNotRoomAdmin", err)
+	}
+}
+
+func TestTransferOwnership(t *testing.T) {
+	env := newTestEnv(t)
+	owner, mod, outsider := env.createUser(t), env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, mod)
+	env.setRole(t, roomID, mod, models.RoomRoleModerator)
+
+	if _, err := env.rooms.TransferOwnership(roomID, owner, mod); !errors.Is(err, ErrNotRoomOwner) {
+		t.Errorf("moderator taking the room: got %v, want ErrNotRoomOwner", err)
+	}
+	if _, err := env.rooms.TransferOwnership(roomID, outsider, owner); !errors.Is(err, ErrMemberNotFound) {
+		t.Errorf("handing the room to an outsider: got %v, want ErrMemberNotFound", err)
+	}
+
+	room, err := env.rooms.TransferOwnership(roomID, mod, owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.OwnerID != mod {
+		t.Errorf("OwnerID = %d, want %d", room.OwnerID, mod)
+	}
+	if got := env.role(t, roomID, owner); got != models.RoomRoleModerator {
+		t.Errorf("previous owner has role %q, want moderator", got)
+	}
+
+	// The previous owner now ranks below the new one
+	if err := env.rooms.RemoveUserFromRoom(roomID, mod, owner); !errors.Is(err, ErrRoomOwnerRemoval) {
+		t.Errorf("previous owner removing the new one: got %v, want ErrRoomOwnerRemoval", err)
+	}
+}
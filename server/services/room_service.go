@@ -1,26 +1,91 @@
 package services
 
 import (
+	"chatingApp/config"
+	"chatingApp/hub"
 	"chatingApp/models"
 	"chatingApp/repository"
 	"errors"
-
-	// "errors"
 	"log"
+	"regexp"
+	"strings"
+)
+
+// Message paging limits for room history.
+const (
+	DefaultMessagePageSize = 50
+	MaxMessagePageSize     = 100
 )
 
+var (
+	// ErrUserNotInRoom is returned when a user acts on a room they are not a member of.
+	ErrUserNotInRoom = errors.New("user is not in the room")
+	// ErrEmptyMessage is returned when a message has no content.
+	ErrEmptyMessage = errors.New("message content is empty")
+	// ErrNotRoomAdmin is returned when an action requires a moderator or the owner of the room.
+	ErrNotRoomAdmin = errors.New("user is not a moderator of the room")
+	// ErrResumeGapTooLarge is returned when a reconnecting client missed too many messages to replay.
+	ErrResumeGapTooLarge = errors.New("too many missed messages to replay")
+	// ErrMessageNotFound is returned when a message does not exist in the given room.
+	ErrMessageNotFound = errors.New("message not found")
+	// ErrNotMessageAuthor is returned when a user changes a message they may not change.
+	ErrNotMessageAuthor = errors.New("user is not allowed to change this message")
+	// ErrInvalidEmoji is returned when a reaction is not a single short emoji.
+	ErrInvalidEmoji = errors.New("invalid emoji")
+	// ErrAttachmentUnavailable is returned when a message is sent with an attachment that does not
+	// exist, was uploaded by someone else or to another room, or is already part of a message.
+	ErrAttachmentUnavailable = errors.New("attachment is not available")
+	// ErrPinLimitReached is returned when a room already has ROOM_MAX_PINS pinned messages.
+	ErrPinLimitReached = errors.New("room has too many pinned messages")
+	// ErrTooManyAttachments is returned when a message carries more than maxMessageAttachments files.
+	ErrTooManyAttachments = errors.New("too many attachments")
+	// ErrInvalidRoomName is returned when a room is renamed to a blank name.
+	ErrInvalidRoomName = errors.New("room name cannot be empty")
+	// ErrUserNotFound is returned when a user being added to a room does not exist.
+	ErrUserNotFound = errors.New("user not found")
+	// ErrNotRoomOwner is returned when an action is reserved for the owner of the room.
+	ErrNotRoomOwner = errors.New("user is not the owner of the room")
+	// ErrMemberNotFound is returned when the target of a membership change is not in the room.
+	ErrMemberNotFound = errors.New("user is not a member of the room")
+	// ErrRoomOwnerRemoval is returned when the owner would be removed or demoted; ownership
+	// has to be transferred first.
+	ErrRoomOwnerRemoval = errors.New("the room owner cannot be removed or demoted; transfer ownership first")
+	// ErrMemberOutranks is returned when a moderator acts on a member whose role is not below theirs.
+	ErrMemberOutranks = errors.New("member's room role is not below yours")
+	// ErrInvalidRoomRole is returned when a member is given a role other than moderator or member.
+	ErrInvalidRoomRole = errors.New("role must be moderator or member")
+)
+
+// mentionPattern matches @handle mentions that are not part of a longer word or email address.
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([\w][\w.-]*)`)
+
+// roomMentionHandle mentions every member of the room.
+const roomMentionHandle = "room"
+
+// maxEmojiLength bounds the size in bytes of a reaction, enough for multi-codepoint emoji.
+const maxEmojiLength = 64
+
+// maxMessageAttachments is how many files one message may carry.
+const maxMessageAttachments = 10
+
 // RoomService provides business logic for chat rooms.
 type RoomService struct {
-	RoomRepo *repository.RoomRepository
+	RoomRepo       *repository.RoomRepository
+	AttachmentRepo *repository.AttachmentRepository
+	Permissions    *RoomPermissionService
+	Hub            *hub.Hub // Used to disconnect sockets of users removed from a room
 }
 
 // NewRoomService creates a new instance of RoomService.
-func NewRoomService(repo *repository.RoomRepository) *RoomService {
-	return &RoomService{RoomRepo: repo}
+func NewRoomService(repo *repository.RoomRepository, attachmentRepo *repository.AttachmentRepository, permissions *RoomPermissionService, chatHub *hub.Hub) *RoomService {
+	return &RoomService{RoomRepo: repo, AttachmentRepo: attachmentRepo, Permissions: permissions, Hub: chatHub}
 }
 
-// CreateRoom creates a new chat room.
+// CreateRoom creates a new chat room. Rooms are private unless given another visibility.
 func (s *RoomService) CreateRoom(room *models.Room) (*models.Room, error) {
+	if room.Visibility == "" {
+		room.Visibility = models.RoomPrivate
+	}
 	createdRoom, err := s.RoomRepo.CreateRoom(room)
 	if err != nil {
 		log.Println("❌ Error: Failed to create room", err)
@@ -40,6 +105,25 @@ func (s *RoomService) GetRoom(roomID int) (*models.Room, error) {
 	return room, nil
 }
 
+// GetVisibleRoom retrieves a room for a caller who may see it: a member, anyone for public and
+// request rooms, or a super-admin. Every other room, like private rooms and conversations the
+// caller is not in, reads as ErrRoomNotFound.
+func (s *RoomService) GetVisibleRoom(roomID, callerID int, callerRole string) (*models.Room, error) {
+	room, err := s.GetRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	listed := room.Kind == models.RoomKindRoom && room.Visibility != models.RoomPrivate
+	if !listed && callerRole != "super-admin" && !s.Permissions.IsMember(roomID, callerID) {
+		return nil, ErrRoomNotFound
+	}
+	return room, nil
+}
+
 // GetAllRooms retrieves all rooms.
 func (s *RoomService) GetAllRooms() ([]models.Room, error) {
 	rooms, err := s.RoomRepo.GetAllRooms()
@@ -52,14 +136,12 @@ func (s *RoomService) GetAllRooms() ([]models.Room, error) {
 
 // DeleteRoom removes a chat room.
 func (s *RoomService) DeleteRoom(roomID, requesterID int) error {
-	// Only room admins can delete rooms
-	exist, err := s.IsUserRoomAdmin(roomID, requesterID)
-	if (err != nil || !exist) {
-		log.Println("❌ Error: User is not an admin of the room")
-		return errors.New("user is not an admin of the room")
+	// Only the owner can delete a room
+	if err := s.Permissions.Require(roomID, requesterID, ActionDeleteRoom); err != nil {
+		return err
 	}
 
-	err = s.RoomRepo.DeleteRoom(roomID)
+	err := s.RoomRepo.DeleteRoom(roomID)
 	if err != nil {
 		log.Println("❌ Error: Failed to delete room", err)
 		return err
@@ -68,144 +150,987 @@ func (s *RoomService) DeleteRoom(roomID, requesterID int) error {
 	return nil
 }
 
-// IsUserRoomAdmin checks if a user is an admin of a room.
-func (s *RoomService) IsUserRoomAdmin(roomID, userID int) (bool, error) {
-	exist, err := s.RoomRepo.IsUserRoomAdmin(userID, roomID)
+// roomMembershipError translates a repository error from a membership change.
+func roomMembershipError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrRoomNotFound):
+		return ErrRoomNotFound
+	case errors.Is(err, repository.ErrUserNotFound):
+		return ErrUserNotFound
+	case errors.Is(err, repository.ErrNotRoomMember):
+		return ErrMemberNotFound
+	case errors.Is(err, repository.ErrNotRoomOwner):
+		return ErrNotRoomOwner
+	case errors.Is(err, repository.ErrRoomOwner):
+		return ErrRoomOwnerRemoval
+	}
+	return err
+}
+
+// UpdateRoomDetails applies the set fields of an update to a room and returns the updated room.
+// Setting Users replaces the member list: new users join as members, and members left out are
+// disconnected from the room. Moderators may only leave out members ranked below them.
+func (s *RoomService) UpdateRoomDetails(roomID, requesterID int, update models.RoomUpdateRequest) (*models.Room, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		name := strings.TrimSpace(*update.Name)
+		if name == "" {
+			return nil, ErrInvalidRoomName
+		}
+		update.Name = &name
+	}
+	if update.Users != nil {
+		users := uniqueIDs(*update.Users)
+		update.Users = &users
+		if err := s.checkMemberRemovals(roomID, requesterID, users); err != nil {
+			return nil, err
+		}
+	}
+
+	removed, err := s.RoomRepo.UpdateRoom(roomID, update)
+	if err != nil {
+		log.Println("❌ Error: Failed to update room details", err)
+		return nil, roomMembershipError(err)
+	}
+	for _, userID := range removed {
+		s.Hub.DisconnectUser(roomID, userID, hub.CloseRemovedFromRoom, "removed from room")
+	}
+
+	log.Println("✅ Room details updated successfully:", roomID)
+	return s.GetRoom(roomID)
+}
+
+// checkMemberRemovals returns ErrMemberOutranks if replacing the member list with users would
+// remove someone the requester does not outrank. The requester may leave themselves out.
+func (s *RoomService) checkMemberRemovals(roomID, requesterID int, users []int) error {
+	members, err := s.RoomRepo.GetRoomMembers(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room members", err)
+		return err
+	}
+
+	keep := make(map[int]bool, len(users))
+	for _, userID := range users {
+		keep[userID] = true
+	}
+	requesterRole := ""
+	for _, member := range members {
+		if member.ID == requesterID {
+			requesterRole = member.RoomRole
+		}
+	}
+	for _, member := range members {
+		if keep[member.ID] || member.ID == requesterID || member.RoomRole == models.RoomRoleOwner {
+			// The repository refuses to drop the owner
+			continue
+		}
+		if !Outranks(requesterRole, member.RoomRole) {
+			return ErrMemberOutranks
+		}
+	}
+	return nil
+}
+
+// SetMemberRole makes a member of a room a moderator or a plain member. Only the owner can
+// change roles, and their own role only changes through TransferOwnership.
+func (s *RoomService) SetMemberRole(roomID, userID, requesterID int, role string) (*models.RoomMember, error) {
+	if role != models.RoomRoleModerator && role != models.RoomRoleMember {
+		return nil, ErrInvalidRoomRole
+	}
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoles); err != nil {
+		return nil, err
+	}
+
+	err := s.RoomRepo.SetMemberRole(roomID, userID, role)
+	if err != nil {
+		log.Println("❌ Error: Failed to change room role", err)
+		return nil, roomMembershipError(err)
+	}
+
+	log.Println("✅ Room role changed successfully:", roomID, userID, role)
+	return s.getRoomMember(roomID, userID)
+}
+
+// TransferOwnership hands a room to another of its members. The previous owner stays on
+// as a moderator.
+func (s *RoomService) TransferOwnership(roomID, newOwnerID, requesterID int) (*models.Room, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionTransferOwnership); err != nil {
+		return nil, err
+	}
+
+	err := s.RoomRepo.TransferOwnership(roomID, requesterID, newOwnerID)
 	if err != nil {
-		log.Println("❌ Error: Failed to check admin status", err)
+		log.Println("❌ Error: Failed to transfer room ownership", err)
+		return nil, roomMembershipError(err)
+	}
+
+	log.Println("✅ Room ownership transferred successfully:", roomID, newOwnerID)
+	return s.GetRoom(roomID)
+}
+
+// getRoomMember retrieves one member of a room.
+func (s *RoomService) getRoomMember(roomID, userID int) (*models.RoomMember, error) {
+	members, err := s.RoomRepo.GetRoomMembers(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room members", err)
+		return nil, err
+	}
+	for i := range members {
+		if members[i].ID == userID {
+			return &members[i], nil
+		}
+	}
+	return nil, ErrMemberNotFound
+}
+
+// AddUserToRoom adds a user to a chat room as a member; the returned bool reports whether
+// they were not in the room yet.
+func (s *RoomService) AddUserToRoom(roomID, userID, requesterID int) (bool, error) {
+	if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
 		return false, err
 	}
-	return exist, nil
-}
-
-
-// // IsUserInRoom checks if a user is a member of a room.
-// func (s *RoomService) IsUserInRoom(roomID, userID int) bool {
-// 	return s.RoomRepo.IsUserInRoom(roomID, userID)
-// }
-
-// // UpdateRoomDetails updates name and description of a room.
-// func (s *RoomService) UpdateRoomDetails(roomID int, name, description string, userID int) error {
-// 	// Only room admins can update room details
-// 	if !s.IsUserRoomAdmin(roomID, userID) {
-// 		log.Println("❌ Error: User is not an admin of the room")
-// 		return errors.New("user is not an admin of the room")
-// 	}
-
-// 	err := s.RoomRepo.UpdateRoomDetails(roomID, name, description)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to update room details", err)
-// 		return err
-// 	}
-// 	log.Println("✅ Room details updated successfully:", roomID)
-// 	return nil
-// }
-
-// // UpdateRoomAdmins updates the admin list of a room.
-// func (s *RoomService) UpdateRoomAdmins(roomID int, roomAdmins []int, userID int) error {
-// 	// Only current admins can update the admin list
-// 	if !s.IsUserRoomAdmin(roomID, userID) {
-// 		log.Println("❌ Error: User is not an admin of the room")
-// 		return errors.New("user is not an admin of the room")
-// 	}
-
-// 	err := s.RoomRepo.UpdateRoomAdmins(roomID, roomAdmins)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to update room admins", err)
-// 		return err
-// 	}
-// 	log.Println("✅ Room admins updated successfully:", roomID)
-// 	return nil
-// }
-
-// AddUserToRoom adds a user to a chat room.
-func (s *RoomService) AddUserToRoom(roomID, userID, requesterID int) error {
-	// Only room admins can add users
-	// if !s.IsUserRoomAdmin(roomID, requesterID) {
-	// 	log.Println("❌ Error: User is not an admin of the room")
-	// 	return errors.New("user is not an admin of the room")
-	// }
-
-	err := s.RoomRepo.AddUserToRoom(roomID, userID)
+
+	added, err := s.RoomRepo.AddUserToRoom(roomID, userID)
 	if err != nil {
 		log.Println("❌ Error: Failed to add user to room", err)
-		return err
+		return false, roomMembershipError(err)
 	}
 	log.Println("✅ User added successfully to room:", roomID)
+	return added, nil
+}
+
+// RemoveUserFromRoom removes a user from a chat room and closes their open sockets to it.
+// Members may remove themselves and moderators may remove anyone ranked below them. The
+// owner cannot be removed until they transfer ownership. Nobody leaves a direct or group
+// conversation.
+func (s *RoomService) RemoveUserFromRoom(roomID, userID, requesterID int) error {
+	room, err := s.GetRoom(roomID)
+	if err != nil {
+		return err
+	}
+	if room != nil && room.Kind != models.RoomKindRoom {
+		return ErrConversationMembership
+	}
+
+	if userID != requesterID {
+		if err := s.Permissions.Require(roomID, requesterID, ActionManageRoom); err != nil {
+			return err
+		}
+		requesterRole, err := s.Permissions.Role(roomID, requesterID)
+		if err != nil {
+			return err
+		}
+		targetRole, err := s.Permissions.Role(roomID, userID)
+		if err != nil {
+			return err
+		}
+		if targetRole == "" {
+			return ErrMemberNotFound
+		}
+		if targetRole != models.RoomRoleOwner && !Outranks(requesterRole, targetRole) {
+			return ErrMemberOutranks
+		}
+	}
+
+	err = s.RoomRepo.RemoveUserFromRoom(roomID, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to remove user from room", err)
+		return roomMembershipError(err)
+	}
+
+	s.Hub.DisconnectUser(roomID, userID, hub.CloseRemovedFromRoom, "removed from room")
+	log.Println("✅ User removed successfully from room:", roomID)
+	return nil
+}
+
+// AddMessageToRoom persists a message sent to a chat room and returns the stored message.
+// A non-zero parentID posts it as a reply in that message's thread; replying to a reply
+// continues the thread of its top-level message. Followers of the thread are notified.
+// attachmentIDs are files the sender uploaded to the room beforehand; a message with
+// attachments may have no text.
+func (s *RoomService) AddMessageToRoom(roomID, userID int, content string, parentID int, attachmentIDs []int) (*models.Message, error) {
+	attachmentIDs = uniqueIDs(attachmentIDs)
+	if strings.TrimSpace(content) == "" && len(attachmentIDs) == 0 {
+		return nil, ErrEmptyMessage
+	}
+	if len(attachmentIDs) > maxMessageAttachments {
+		return nil, ErrTooManyAttachments
+	}
+
+	// Ensure user is in the room before adding a message
+	if !s.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	var root *models.Message
+	if parentID != 0 {
+		var err error
+		root, err = s.getThreadRoot(roomID, parentID)
+		if err != nil {
+			return nil, err
+		}
+		if root.DeletedAt != nil {
+			return nil, ErrMessageNotFound
+		}
+		parentID = root.ID
+	}
+
+	message, firstReply, err := s.RoomRepo.AddMessageToRoom(roomID, userID, content, parentID, attachmentIDs)
+	if errors.Is(err, repository.ErrAttachmentUnavailable) {
+		return nil, ErrAttachmentUnavailable
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to add message to room", err)
+		return nil, err
+	}
+	log.Println("✅ Message added successfully to room:", roomID)
+
+	s.recordMentions(message)
+	if root != nil {
+		s.notifyThreadFollowers(root, message, firstReply)
+	}
+	return message, nil
+}
+
+// PublishMessage finishes posting a message that was stored without AddMessageToRoom, such
+// as a scheduled one: its mentions are recorded, thread followers are notified and it is
+// broadcast to the room like any other message. firstReply reports whether the message was
+// the first reply in its thread when it was stored.
+func (s *RoomService) PublishMessage(message *models.Message, firstReply bool) {
+	s.recordMentions(message)
+	if message.ParentID != nil {
+		root, err := s.RoomRepo.GetMessageByID(*message.ParentID)
+		if err != nil {
+			log.Println("❌ Error: Failed to retrieve thread", err)
+		} else if root != nil {
+			s.notifyThreadFollowers(root, message, firstReply)
+		}
+	}
+	s.Hub.BroadcastSeqEvent(message.RoomID, message.Seq, message.EventType(), message)
+}
+
+// notifyThreadFollowers makes the author of a reply follow its thread and sends the
+// reply to every other follower, even those not subscribed to the room. The thread's
+// starter follows it from the first reply on.
+func (s *RoomService) notifyThreadFollowers(root, reply *models.Message, firstReply bool) {
+	if err := s.RoomRepo.FollowThread(root.ID, reply.UserID); err != nil {
+		log.Println("❌ Error: Failed to follow thread", err)
+	}
+
+	if firstReply {
+		if err := s.RoomRepo.FollowThread(root.ID, root.UserID); err != nil {
+			log.Println("❌ Error: Failed to follow thread", err)
+		}
+	}
+
+	followers, err := s.RoomRepo.GetThreadFollowers(root.ID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve thread followers", err)
+		return
+	}
+
+	recipients := make([]int, 0, len(followers))
+	for _, userID := range followers {
+		if userID != reply.UserID {
+			recipients = append(recipients, userID)
+		}
+	}
+	s.Hub.SendToUsers(recipients, reply.RoomID, models.EventThreadUpdated, reply)
+}
+
+// recordMentions stores the @mentions in a message, resolved against the members of its
+// room, and notifies each newly mentioned user on every connection they have open.
+// The author is never mentioned by their own message.
+func (s *RoomService) recordMentions(message *models.Message) {
+	handles, roomWide := parseMentions(message.Content)
+	if len(handles) == 0 && !roomWide {
+		return
+	}
+
+	var mentions []models.Mention
+	if len(handles) > 0 {
+		users, err := s.RoomRepo.ResolveMentionHandles(message.RoomID, handles)
+		if err != nil {
+			log.Println("❌ Error: Failed to resolve mentions", err)
+			return
+		}
+		added, err := s.addMentions(message, users, models.MentionUser)
+		if err != nil {
+			return
+		}
+		mentions = append(mentions, added...)
+	}
+	if roomWide {
+		users, err := s.GetUsersInRoom(message.RoomID)
+		if err != nil {
+			return
+		}
+		added, err := s.addMentions(message, users, models.MentionRoom)
+		if err != nil {
+			return
+		}
+		mentions = append(mentions, added...)
+	}
+
+	for i := range mentions {
+		mentions[i].Message = message
+		s.Hub.SendToUsers([]int{mentions[i].UserID}, message.RoomID, models.EventMention, mentions[i])
+	}
+}
+
+// addMentions stores mentions of users other than the message's author.
+func (s *RoomService) addMentions(message *models.Message, users []int, kind string) ([]models.Mention, error) {
+	recipients := make([]int, 0, len(users))
+	for _, userID := range users {
+		if userID != message.UserID {
+			recipients = append(recipients, userID)
+		}
+	}
+	if len(recipients) == 0 {
+		return nil, nil
+	}
+
+	mentions, err := s.RoomRepo.AddMentions(message, recipients, kind)
+	if err != nil {
+		log.Println("❌ Error: Failed to store mentions", err)
+		return nil, err
+	}
+	return mentions, nil
+}
+
+// parseMentions extracts the lowercase @handles from message content, and whether it
+// mentions the whole room with @room.
+func parseMentions(content string) ([]string, bool) {
+	var handles []string
+	roomWide := false
+	seen := make(map[string]bool)
+
+	for _, match := range mentionPattern.FindAllStringSubmatch(content, -1) {
+		// Trailing punctuation ends a sentence, not a handle
+		handle := strings.ToLower(strings.TrimRight(match[1], ".-"))
+		if handle == roomMentionHandle {
+			roomWide = true
+			continue
+		}
+		if !seen[handle] {
+			seen[handle] = true
+			handles = append(handles, handle)
+		}
+	}
+	return handles, roomWide
+}
+
+// GetMentionInbox retrieves a page of a user's mentions, newest first, with the number
+// of unread ones. before is a mention ID cursor; zero starts from the newest.
+func (s *RoomService) GetMentionInbox(userID, before, limit int, unreadOnly bool) (*models.MentionInboxResponse, error) {
+	limit = clampPageSize(limit)
+	mentions, err := s.RoomRepo.GetMentionsByUserID(userID, before, limit+1, unreadOnly)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve mentions", err)
+		return nil, err
+	}
+
+	hasMore := len(mentions) > limit
+	if hasMore {
+		mentions = mentions[:limit]
+	}
+
+	unread, err := s.RoomRepo.CountUnreadMentions(userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to count unread mentions", err)
+		return nil, err
+	}
+
+	return &models.MentionInboxResponse{Mentions: mentions, UnreadCount: unread, HasMore: hasMore}, nil
+}
+
+// MarkMentionsRead marks the given mentions of a user as read, or all of them if ids is empty.
+func (s *RoomService) MarkMentionsRead(userID int, ids []int) error {
+	if err := s.RoomRepo.MarkMentionsRead(userID, ids); err != nil {
+		log.Println("❌ Error: Failed to mark mentions as read", err)
+		return err
+	}
+	return nil
+}
+
+// GetThread retrieves a page of replies in the thread of a top-level message, oldest first,
+// with the same cursors as GetMessagesByRoomID.
+func (s *RoomService) GetThread(roomID, messageID, requesterID, before, after, limit int) (*models.ThreadResponse, error) {
+	if !s.Permissions.IsMember(roomID, requesterID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	parent, err := s.getThreadRoot(roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	limit = clampPageSize(limit)
+	replies, err := s.RoomRepo.GetThreadReplies(parent.ID, before, after, limit+1)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve thread replies", err)
+		return nil, err
+	}
+	replies, hasMore := trimPage(replies, limit, after > 0 && before == 0)
+
+	// The parent goes through the same pass as its replies
+	listed := append([]models.Message{*parent}, replies...)
+	if err := s.attachReactions(listed, requesterID); err != nil {
+		return nil, err
+	}
+	if err := s.attachAttachments(listed); err != nil {
+		return nil, err
+	}
+	parent, replies = &listed[0], listed[1:]
+
+	following, err := s.RoomRepo.IsFollowingThread(parent.ID, requesterID)
+	if err != nil {
+		log.Println("❌ Error: Failed to check thread follow status", err)
+		return nil, err
+	}
+
+	return &models.ThreadResponse{Parent: *parent, Replies: replies, HasMore: hasMore, Following: following}, nil
+}
+
+// SetThreadFollow makes a room member follow or unfollow the thread of a message.
+func (s *RoomService) SetThreadFollow(roomID, messageID, userID int, follow bool) error {
+	if !s.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return ErrUserNotInRoom
+	}
+
+	parent, err := s.getThreadRoot(roomID, messageID)
+	if err != nil {
+		return err
+	}
+
+	if follow {
+		err = s.RoomRepo.FollowThread(parent.ID, userID)
+	} else {
+		err = s.RoomRepo.UnfollowThread(parent.ID, userID)
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to update thread follow status", err)
+		return err
+	}
+	return nil
+}
+
+// getThreadRoot retrieves the top-level message of the thread a message belongs to.
+func (s *RoomService) getThreadRoot(roomID, messageID int) (*models.Message, error) {
+	message, err := s.getRoomMessage(roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.ParentID == nil {
+		return message, nil
+	}
+	return s.getRoomMessage(roomID, *message.ParentID)
+}
+
+// GetRoomLastSeq retrieves the sequence number of the newest message in a room.
+func (s *RoomService) GetRoomLastSeq(roomID int) (int64, error) {
+	lastSeq, err := s.RoomRepo.GetRoomLastSeq(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room sequence", err)
+		return 0, err
+	}
+	return lastSeq, nil
+}
+
+// GetMessagesSinceSeq retrieves the messages of a room after lastSeq, for replay to a
+// reconnecting client, with their reactions as seen by requesterID. When more than maxGap
+// messages were missed it returns ErrResumeGapTooLarge; the returned sequence number is
+// always the room's newest one.
+func (s *RoomService) GetMessagesSinceSeq(roomID, requesterID int, lastSeq int64, maxGap int) ([]models.Message, int64, error) {
+	latest, err := s.RoomRepo.GetRoomLastSeq(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room sequence", err)
+		return nil, 0, err
+	}
+
+	if latest-lastSeq > int64(maxGap) {
+		return nil, latest, ErrResumeGapTooLarge
+	}
+	if latest <= lastSeq {
+		return []models.Message{}, latest, nil
+	}
+
+	messages, err := s.RoomRepo.GetMessagesAfterSeq(roomID, lastSeq, maxGap)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve missed messages", err)
+		return nil, 0, err
+	}
+	if err := s.attachReactions(messages, requesterID); err != nil {
+		return nil, 0, err
+	}
+	if err := s.attachAttachments(messages); err != nil {
+		return nil, 0, err
+	}
+	return messages, latest, nil
+}
+
+// GetUsersInRoom retrieves all users in a room.
+func (s *RoomService) GetUsersInRoom(roomID int) ([]int, error) {
+	users, err := s.RoomRepo.GetUsersInRoom(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve users in room", err)
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetRoomMembers retrieves the members of a room as user objects. Only members may list them.
+func (s *RoomService) GetRoomMembers(roomID, requesterID int) ([]models.RoomMember, error) {
+	if !s.Permissions.IsMember(roomID, requesterID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	members, err := s.RoomRepo.GetRoomMembers(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve room members", err)
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetRoomsByUserID retrieves all rooms a user is a member of.
+func (s *RoomService) GetRoomsByUserID(userID int) ([]models.Room, error) {
+	rooms, err := s.RoomRepo.GetRoomsByUserID(userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve rooms for user", err)
+		return nil, err
+	}
+	return rooms, nil
+}
+
+// MarkRoomRead moves a user's read marker in a room forward to a message and, if it
+// moved, tells the other members with a read receipt. Markers never move backwards.
+func (s *RoomService) MarkRoomRead(roomID, userID, messageID int) (*models.ReadReceipt, error) {
+	if !s.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	if _, err := s.getRoomMessage(roomID, messageID); err != nil {
+		return nil, err
+	}
+
+	// Reading a room also reads the mentions in it
+	if err := s.RoomRepo.MarkRoomMentionsRead(userID, roomID, messageID); err != nil {
+		log.Println("❌ Error: Failed to mark mentions as read", err)
+	}
+
+	receipt, moved, err := s.RoomRepo.UpdateReadMarker(roomID, userID, messageID)
+	if err != nil {
+		log.Println("❌ Error: Failed to update read marker", err)
+		return nil, err
+	}
+
+	if moved {
+		s.Hub.BroadcastEvent(roomID, models.EventReadReceipt, receipt)
+	}
+	return receipt, nil
+}
+
+// GetRoomSummaries retrieves the rooms a user belongs to with unread counts and last messages.
+func (s *RoomService) GetRoomSummaries(userID int) ([]models.RoomSummary, error) {
+	summaries, err := s.RoomRepo.GetRoomSummariesByUserID(userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve rooms for user", err)
+		return nil, err
+	}
+	return summaries, nil
+}
+
+// BroadcastPresence tells every room a user belongs to that the user's status changed.
+func (s *RoomService) BroadcastPresence(userID int, status string) {
+	rooms, err := s.GetRoomsByUserID(userID)
+	if err != nil {
+		return
+	}
+	for _, room := range rooms {
+		s.Hub.BroadcastEvent(room.ID, models.EventPresence, models.PresencePayload{UserID: userID, Status: status})
+	}
+}
+
+// GetRoomPresence retrieves the live status of every member of a room.
+func (s *RoomService) GetRoomPresence(roomID, requesterID int) ([]models.UserPresence, error) {
+	if !s.Permissions.IsMember(roomID, requesterID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	users, err := s.GetUsersInRoom(roomID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := s.Hub.Presence.Statuses(users)
+	presence := make([]models.UserPresence, 0, len(users))
+	for _, userID := range users {
+		presence = append(presence, models.UserPresence{UserID: userID, Status: statuses[userID]})
+	}
+	return presence, nil
+}
+
+// GetMessagesByRoomID retrieves a page of messages from a chat room, oldest first.
+// before and after are message ID cursors (zero means unbounded); the returned bool
+// reports whether more messages exist beyond the page in the paging direction.
+func (s *RoomService) GetMessagesByRoomID(roomID, requesterID, before, after, limit int) ([]models.Message, bool, error) {
+	if !s.Permissions.IsMember(roomID, requesterID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, false, ErrUserNotInRoom
+	}
+
+	limit = clampPageSize(limit)
+
+	// Fetch one extra row to find out whether another page exists
+	messages, err := s.RoomRepo.GetMessagesByRoomID(roomID, before, after, limit+1)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve messages for room", err)
+		return nil, false, err
+	}
+
+	messages, hasMore := trimPage(messages, limit, after > 0 && before == 0)
+	if err := s.attachReactions(messages, requesterID); err != nil {
+		return nil, false, err
+	}
+	if err := s.attachAttachments(messages); err != nil {
+		return nil, false, err
+	}
+	return messages, hasMore, nil
+}
+
+// clampPageSize applies the default and maximum page size to a requested limit.
+func clampPageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultMessagePageSize
+	}
+	if limit > MaxMessagePageSize {
+		return MaxMessagePageSize
+	}
+	return limit
+}
+
+// trimPage cuts a page fetched with one extra row back to limit and reports whether
+// the extra row existed. The extra row is the newest one when paging forward and the
+// oldest one otherwise.
+func trimPage(messages []models.Message, limit int, ascending bool) ([]models.Message, bool) {
+	if len(messages) <= limit {
+		return messages, false
+	}
+	if ascending {
+		return messages[:limit], true
+	}
+	return messages[1:], true
+}
+
+// getRoomMessage retrieves a message that belongs to roomID, or ErrMessageNotFound.
+func (s *RoomService) getRoomMessage(roomID, messageID int) (*models.Message, error) {
+	message, err := s.RoomRepo.GetMessageByID(messageID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve message", err)
+		return nil, err
+	}
+	if message == nil || message.RoomID != roomID {
+		return nil, ErrMessageNotFound
+	}
+	return message, nil
+}
+
+// EditMessage replaces the content of a message and broadcasts message.edited to the room.
+// Only the author may edit, and deleted messages cannot be edited.
+func (s *RoomService) EditMessage(roomID, messageID, userID int, content string) (*models.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	if !s.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	message, err := s.getRoomMessage(roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+	if message.UserID != userID {
+		log.Println("❌ Error: User is not the author of the message")
+		return nil, ErrNotMessageAuthor
+	}
+
+	edited, err := s.RoomRepo.EditMessage(messageID, userID, content)
+	if err != nil {
+		log.Println("❌ Error: Failed to edit message", err)
+		return nil, err
+	}
+	if edited == nil {
+		// Deleted between the check and the update
+		return nil, ErrMessageNotFound
+	}
+
+	// Editing changes the text only; the files go out with the message again
+	edits := []models.Message{*edited}
+	if err := s.attachAttachments(edits); err != nil {
+		return nil, err
+	}
+	edited = &edits[0]
+
+	s.Hub.BroadcastEvent(roomID, models.EventMessageEdited, edited)
+	s.recordMentions(edited) // Only users newly mentioned by the edit are notified
+	log.Println("✅ Message edited successfully:", messageID)
+	return edited, nil
+}
+
+// DeleteMessage soft-deletes a message, leaving a tombstone, and broadcasts
+// message.deleted to the room. Authors may delete their own messages, and
+// moderators and the owner any message in the room.
+func (s *RoomService) DeleteMessage(roomID, messageID, userID int) (*models.Message, error) {
+	message, err := s.getRoomMessage(roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.DeletedAt != nil {
+		return nil, ErrMessageNotFound
+	}
+
+	if message.UserID != userID || !s.Permissions.IsMember(roomID, userID) {
+		canModerate, err := s.Permissions.Can(roomID, userID, ActionModerate)
+		if err != nil || !canModerate {
+			log.Println("❌ Error: User may not delete the message")
+			return nil, ErrNotMessageAuthor
+		}
+	}
+
+	deleted, err := s.RoomRepo.SoftDeleteMessage(messageID, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to delete message", err)
+		return nil, err
+	}
+	if deleted == nil {
+		return nil, ErrMessageNotFound
+	}
+
+	s.Hub.BroadcastEvent(roomID, models.EventMessageDeleted, deleted)
+	log.Println("✅ Message deleted successfully:", messageID)
+
+	// A deleted message no longer counts towards the room's pins
+	unpinned, err := s.RoomRepo.UnpinMessage(messageID)
+	if err != nil {
+		log.Println("❌ Error: Failed to unpin deleted message", err)
+	} else if unpinned {
+		s.Hub.BroadcastEvent(roomID, models.EventMessageUnpinned, models.PinPayload{MessageID: messageID, UserID: userID})
+	}
+	return deleted, nil
+}
+
+// PinMessage pins a message in its room and broadcasts message.pinned if it was not pinned
+// yet. Only moderators and the owner may pin, and at most ROOM_MAX_PINS messages per room.
+func (s *RoomService) PinMessage(roomID, messageID, userID int) error {
+	return s.setPin(roomID, messageID, userID, true)
+}
+
+// UnpinMessage unpins a message and broadcasts message.unpinned if it was pinned.
+// Only moderators and the owner may unpin.
+func (s *RoomService) UnpinMessage(roomID, messageID, userID int) error {
+	return s.setPin(roomID, messageID, userID, false)
+}
+
+func (s *RoomService) setPin(roomID, messageID, userID int, pin bool) error {
+	if err := s.Permissions.Require(roomID, userID, ActionModerate); err != nil {
+		return err
+	}
+
+	message, err := s.getRoomMessage(roomID, messageID)
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	eventType := models.EventMessageUnpinned
+	if pin {
+		if message.DeletedAt != nil {
+			return ErrMessageNotFound
+		}
+		changed, err = s.RoomRepo.PinMessage(roomID, messageID, userID, config.AppConfig.RoomMaxPins)
+		if errors.Is(err, repository.ErrPinLimitReached) {
+			return ErrPinLimitReached
+		}
+		eventType = models.EventMessagePinned
+	} else {
+		changed, err = s.RoomRepo.UnpinMessage(messageID)
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to update pin", err)
+		return err
+	}
+
+	if changed {
+		s.Hub.BroadcastEvent(roomID, eventType, models.PinPayload{MessageID: messageID, UserID: userID})
+	}
+	return nil
+}
+
+// GetPinnedMessages retrieves the pinned messages of a room, most recently pinned first.
+func (s *RoomService) GetPinnedMessages(roomID, requesterID int) ([]models.PinnedMessage, error) {
+	if !s.Permissions.IsMember(roomID, requesterID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	pins, err := s.RoomRepo.GetPinnedMessages(roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve pinned messages", err)
+		return nil, err
+	}
+
+	messages := make([]models.Message, len(pins))
+	for i := range pins {
+		messages[i] = pins[i].Message
+	}
+	if err := s.attachReactions(messages, requesterID); err != nil {
+		return nil, err
+	}
+	if err := s.attachAttachments(messages); err != nil {
+		return nil, err
+	}
+	for i := range pins {
+		pins[i].Message = messages[i]
+	}
+	return pins, nil
+}
+
+// AddReaction records a member's emoji reaction to a message and broadcasts
+// reaction.added to the room if it is new.
+func (s *RoomService) AddReaction(roomID, messageID, userID int, emoji string) error {
+	return s.setReaction(roomID, messageID, userID, emoji, true)
+}
+
+// RemoveReaction takes back a member's emoji reaction to a message and broadcasts
+// reaction.removed to the room if there was one.
+func (s *RoomService) RemoveReaction(roomID, messageID, userID int, emoji string) error {
+	return s.setReaction(roomID, messageID, userID, emoji, false)
+}
+
+func (s *RoomService) setReaction(roomID, messageID, userID int, emoji string, add bool) error {
+	emoji = strings.TrimSpace(emoji)
+	if emoji == "" || len(emoji) > maxEmojiLength || strings.ContainsAny(emoji, " \t\n") {
+		return ErrInvalidEmoji
+	}
+
+	if !s.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return ErrUserNotInRoom
+	}
+
+	message, err := s.getRoomMessage(roomID, messageID)
+	if err != nil {
+		return err
+	}
+
+	var changed bool
+	eventType := models.EventReactionRemoved
+	if add {
+		if message.DeletedAt != nil {
+			return ErrMessageNotFound
+		}
+		changed, err = s.RoomRepo.AddReaction(messageID, userID, emoji)
+		eventType = models.EventReactionAdded
+	} else {
+		changed, err = s.RoomRepo.RemoveReaction(messageID, userID, emoji)
+	}
+	if err != nil {
+		log.Println("❌ Error: Failed to update reaction", err)
+		return err
+	}
+
+	if changed {
+		s.Hub.BroadcastEvent(roomID, eventType, models.ReactionPayload{MessageID: messageID, UserID: userID, Emoji: emoji})
+	}
+	return nil
+}
+
+// attachReactions fills in the aggregated reactions of listed messages as seen by userID.
+func (s *RoomService) attachReactions(messages []models.Message, userID int) error {
+	ids := make([]int, len(messages))
+	for i := range messages {
+		ids[i] = messages[i].ID
+	}
+
+	counts, err := s.RoomRepo.GetReactionCounts(ids, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve reactions", err)
+		return err
+	}
+	for i := range messages {
+		messages[i].Reactions = counts[messages[i].ID]
+	}
 	return nil
 }
 
-// // RemoveUserFromRoom removes a user from a chat room.
-// func (s *RoomService) RemoveUserFromRoom(roomID, userID, requesterID int) error {
-// 	// Only room admins can remove users
-// 	if !s.IsUserRoomAdmin(roomID, requesterID) {
-// 		log.Println("❌ Error: User is not an admin of the room")
-// 		return errors.New("user is not an admin of the room")
-// 	}
-
-// 	err := s.RoomRepo.RemoveUserFromRoom(roomID, userID)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to remove user from room", err)
-// 		return err
-// 	}
-// 	log.Println("✅ User removed successfully from room:", roomID)
-// 	return nil
-// }
-
-// // AddMessageToRoom adds a message to a chat room.
-// func (s *RoomService) AddMessageToRoom(roomID, userID int, content string) error {
-// 	// Ensure user is in the room before adding a message
-// 	if !s.RoomRepo.IsUserInRoom(roomID, userID) {
-// 		log.Println("❌ Error: User is not in the room")
-// 		return errors.New("user is not in the room")
-// 	}
-
-// 	err := s.RoomRepo.AddMessageToRoom(roomID, userID, content)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to add message to room", err)
-// 		return err
-// 	}
-// 	log.Println("✅ Message added successfully to room:", roomID)
-// 	return nil
-// }
-
-// // GetUsersInRoom retrieves all users in a room.
-// func (s *RoomService) GetUsersInRoom(roomID int) ([]int, error) {
-// 	users, err := s.RoomRepo.GetUsersInRoom(roomID)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to retrieve users in room", err)
-// 		return nil, err
-// 	}
-// 	return users, nil
-// }
-
-// // GetRoomsByUserID retrieves all rooms a user is a member of.
-// func (s *RoomService) GetRoomsByUserID(userID int) ([]models.Room, error) {
-// 	rooms, err := s.RoomRepo.GetRoomsByUserID(userID)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to retrieve rooms for user", err)
-// 		return nil, err
-// 	}
-// 	return rooms, nil
-// }
-
-// // GetMessagesByRoomID retrieves all messages from a chat room.
-// func (s *RoomService) GetMessagesByRoomID(roomID int) ([]models.Message, error) {
-// 	messages, err := s.RoomRepo.GetMessagesByRoomID(roomID)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to retrieve messages for room", err)
-// 		return nil, err
-// 	}
-// 	return messages, nil
-// }
-
-// // DeleteMessageByID deletes a message from a chat room.
-// func (s *RoomService) DeleteMessageByID(messageID, requesterID int) error {
-// 	err := s.RoomRepo.DeleteMessageByID(messageID)
-// 	if err != nil {
-// 		log.Println("❌ Error: Failed to delete message", err)
-// 		return err
-// 	}
-// 	log.Println("✅ Message deleted successfully:", messageID)
-// 	return nil
-// }
+// attachAttachments fills in the files of listed messages. Tombstones of deleted messages keep none.
+func (s *RoomService) attachAttachments(messages []models.Message) error {
+	ids := make([]int, 0, len(messages))
+	for i := range messages {
+		if messages[i].DeletedAt == nil {
+			ids = append(ids, messages[i].ID)
+		}
+	}
+
+	attachments, err := s.AttachmentRepo.GetAttachmentsByMessageIDs(ids)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve attachments", err)
+		return err
+	}
+	for i := range messages {
+		messages[i].Attachments = attachments[messages[i].ID]
+	}
+	return nil
+}
+
+// uniqueIDs drops repeated IDs, keeping the first occurrence of each.
+func uniqueIDs(ids []int) []int {
+	seen := make(map[int]bool, len(ids))
+	unique := ids[:0:0]
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// GetMessageHistory retrieves the previous versions of a message. Deleted content is
+// kept here too, so only the author, moderators and the owner may read it.
+func (s *RoomService) GetMessageHistory(roomID, messageID, userID int) ([]models.MessageEdit, error) {
+	message, err := s.getRoomMessage(roomID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	if message.UserID != userID || !s.Permissions.IsMember(roomID, userID) {
+		canModerate, err := s.Permissions.Can(roomID, userID, ActionModerate)
+		if err != nil || !canModerate {
+			log.Println("❌ Error: User may not read the message history")
+			return nil, ErrNotMessageAuthor
+		}
+	}
+
+	edits, err := s.RoomRepo.GetMessageEdits(messageID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve message history", err)
+		return nil, err
+	}
+	return edits, nil
+}
@@ -0,0 +1,209 @@
+package services
+
+import (
+	"chatingApp/models"
+	"chatingApp/repository"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxScheduleAhead is how far in the future a message may be scheduled.
+const maxScheduleAhead = 365 * 24 * time.Hour
+
+var (
+	// ErrScheduledMessageNotFound is returned when a scheduled message does not exist, belongs to
+	// someone else or is no longer pending.
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	// ErrInvalidSendTime is returned when a send time is in the past or too far ahead.
+	ErrInvalidSendTime = errors.New("send time must be in the future and within a year")
+)
+
+// ScheduledMessageService provides business logic for messages posted at a later time,
+// and runs the dispatcher that posts them when they come due.
+type ScheduledMessageService struct {
+	ScheduledRepo *repository.ScheduledMessageRepository
+	RoomService   *RoomService // Posts dispatched messages through the normal room pipeline
+
+	stop     chan struct{}
+	done     chan struct{}
+	started  bool
+	stopOnce sync.Once
+}
+
+// NewScheduledMessageService creates a new instance of ScheduledMessageService.
+func NewScheduledMessageService(repo *repository.ScheduledMessageRepository, roomService *RoomService) *ScheduledMessageService {
+	return &ScheduledMessageService{
+		ScheduledRepo: repo,
+		RoomService:   roomService,
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+}
+
+// ScheduleMessage stores a message from a room member to be posted at sendAt. A non-zero
+// parentID posts it in that message's thread.
+func (s *ScheduledMessageService) ScheduleMessage(roomID, userID int, content string, parentID int, sendAt time.Time) (*models.ScheduledMessage, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, ErrEmptyMessage
+	}
+	if err := checkSendTime(sendAt); err != nil {
+		return nil, err
+	}
+
+	if !s.RoomService.Permissions.IsMember(roomID, userID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	scheduled := &models.ScheduledMessage{RoomID: roomID, UserID: userID, Content: content, SendAt: sendAt}
+	if parentID != 0 {
+		root, err := s.RoomService.getThreadRoot(roomID, parentID)
+		if err != nil {
+			return nil, err
+		}
+		if root.DeletedAt != nil {
+			return nil, ErrMessageNotFound
+		}
+		scheduled.ParentID = &root.ID
+	}
+
+	created, err := s.ScheduledRepo.CreateScheduledMessage(scheduled)
+	if err != nil {
+		log.Println("❌ Error: Failed to schedule message", err)
+		return nil, err
+	}
+	log.Println("✅ Message scheduled successfully in room:", roomID)
+	return created, nil
+}
+
+// GetPendingMessages retrieves the caller's pending scheduled messages, soonest first.
+// A non-zero roomID keeps only those for that room.
+func (s *ScheduledMessageService) GetPendingMessages(userID, roomID int) ([]models.ScheduledMessage, error) {
+	scheduled, err := s.ScheduledRepo.GetPendingByUserID(userID, roomID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve scheduled messages", err)
+		return nil, err
+	}
+	return scheduled, nil
+}
+
+// UpdateScheduledMessage changes the content or send time of the caller's pending scheduled message.
+func (s *ScheduledMessageService) UpdateScheduledMessage(id, userID int, content *string, sendAt *time.Time) (*models.ScheduledMessage, error) {
+	current, err := s.ScheduledRepo.GetPendingByID(id, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to retrieve scheduled message", err)
+		return nil, err
+	}
+	if current == nil {
+		return nil, ErrScheduledMessageNotFound
+	}
+
+	if content != nil {
+		if strings.TrimSpace(*content) == "" {
+			return nil, ErrEmptyMessage
+		}
+		current.Content = *content
+	}
+	if sendAt != nil {
+		if err := checkSendTime(*sendAt); err != nil {
+			return nil, err
+		}
+		current.SendAt = *sendAt
+	}
+
+	updated, err := s.ScheduledRepo.UpdatePending(id, userID, current.Content, current.SendAt)
+	if err != nil {
+		log.Println("❌ Error: Failed to update scheduled message", err)
+		return nil, err
+	}
+	if updated == nil {
+		// Dispatched or cancelled in the meantime
+		return nil, ErrScheduledMessageNotFound
+	}
+	return updated, nil
+}
+
+// CancelScheduledMessage cancels the caller's pending scheduled message.
+func (s *ScheduledMessageService) CancelScheduledMessage(id, userID int) error {
+	cancelled, err := s.ScheduledRepo.CancelPending(id, userID)
+	if err != nil {
+		log.Println("❌ Error: Failed to cancel scheduled message", err)
+		return err
+	}
+	if !cancelled {
+		return ErrScheduledMessageNotFound
+	}
+	log.Println("✅ Scheduled message cancelled:", id)
+	return nil
+}
+
+// checkSendTime rejects send times in the past or more than maxScheduleAhead away.
+func checkSendTime(sendAt time.Time) error {
+	now := time.Now()
+	if !sendAt.After(now) || sendAt.After(now.Add(maxScheduleAhead)) {
+		return ErrInvalidSendTime
+	}
+	return nil
+}
+
+// StartDispatcher posts due scheduled messages every interval until StopDispatcher is
+// called. Messages that came due while the server was down are posted right away.
+func (s *ScheduledMessageService) StartDispatcher(interval time.Duration) {
+	s.started = true
+	go func() {
+		defer close(s.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			s.dispatchDue()
+			select {
+			case <-ticker.C:
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+	log.Println("✅ Success: Scheduled message dispatcher started")
+}
+
+// StopDispatcher stops the dispatcher and waits for a running dispatch to finish.
+func (s *ScheduledMessageService) StopDispatcher() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+		if s.started {
+			<-s.done
+		}
+	})
+}
+
+// dispatchDue posts every scheduled message that is due, one transaction each.
+func (s *ScheduledMessageService) dispatchDue() {
+	for {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+
+		scheduled, message, firstReply, err := s.ScheduledRepo.DispatchNext()
+		if err != nil {
+			log.Println("❌ Error: Failed to dispatch scheduled message", err)
+			return
+		}
+		if scheduled == nil {
+			return
+		}
+
+		if message == nil {
+			log.Println("⚠️ Warning: Scheduled message could not be posted:", scheduled.ID)
+			continue
+		}
+		log.Println("✅ Scheduled message posted to room:", scheduled.RoomID)
+		s.RoomService.PublishMessage(message, firstReply)
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"chatingApp/models"
+	"chatingApp/repository"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// scheduleDue stores a pending scheduled message that is already due and returns its ID.
+// A non-zero parentID makes it a reply in that message's thread.
+func (e *testEnv) scheduleDue(t *testing.T, roomID, userID int, content string, parentID int) int {
+	t.Helper()
+	var parent *int
+	if parentID != 0 {
+		parent = &parentID
+	}
+	var id int
+	err := e.db.QueryRow(`INSERT INTO scheduled_messages (room_id, user_id, parent_id, content, send_at)
+						  VALUES ($1, $2, $3, $4, NOW() - INTERVAL '1 minute') RETURNING id;`,
+		roomID, userID, parent, content).Scan(&id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestDispatchNextPostsEachMessageOnce(t *testing.T) {
+	env := newTestEnv(t)
+	owner := env.createUser(t)
+	roomID := env.createRoom(t, owner)
+
+	const due = 30
+	for i := 0; i < due; i++ {
+		env.scheduleDue(t, roomID, owner, fmt.Sprintf("scheduled %d", i), 0)
+	}
+	// Not due yet, so never picked up
+	if _, err := env.db.Exec(`INSERT INTO scheduled_messages (room_id, user_id, content, send_at)
+							  VALUES ($1, $2, 'later', NOW() + INTERVAL '1 hour');`, roomID, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	// Several dispatchers, as on several replicas, drain the queue at the same time
+	posted := make(chan int, due)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repo := repository.NewScheduledMessageRepository(env.db)
+			for {
+				scheduled, message, _, err := repo.DispatchNext()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if scheduled == nil {
+					return
+				}
+				if message == nil || scheduled.Status != models.ScheduledSent {
+					t.Errorf("scheduled message %d was not posted: status %q", scheduled.ID, scheduled.Status)
+					continue
+				}
+				posted <- scheduled.ID
+			}
+		}()
+	}
+	wg.Wait()
+	close(posted)
+
+	seen := make(map[int]bool)
+	for id := range posted {
+		if seen[id] {
+			t.Errorf("scheduled message %d posted twice", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != due {
+		t.Errorf("%d scheduled messages posted, want %d", len(seen), due)
+	}
+
+	var messages, sent int
+	if err := env.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE room_id = $1;`, roomID).Scan(&messages); err != nil {
+		t.Fatal(err)
+	}
+	if err := env.db.QueryRow(`SELECT COUNT(*) FROM scheduled_messages WHERE status = 'sent' AND message_id IS NOT NULL;`).Scan(&sent); err != nil {
+		t.Fatal(err)
+	}
+	if messages != due || sent != due {
+		t.Errorf("%d messages stored and %d marked sent, want %d each", messages, sent, due)
+	}
+
+	// After a restart, sent messages are not posted again
+	scheduled, _, _, err := repository.NewScheduledMessageRepository(env.db).DispatchNext()
+	if err != nil || scheduled != nil {
+		t.Errorf("dispatch after restart returned %v (%v), want nothing", scheduled, err)
+	}
+}
+
+func TestDispatchNextFailsWhenAuthorLeft(t *testing.T) {
+	env := newTestEnv(t)
+	owner, member := env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, member)
+	id := env.scheduleDue(t, roomID, member, "too late", 0)
+
+	if err := env.rooms.RemoveUserFromRoom(roomID, member, member); err != nil {
+		t.Fatal(err)
+	}
+
+	scheduled, message, _, err := repository.NewScheduledMessageRepository(env.db).DispatchNext()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scheduled == nil || scheduled.ID != id || scheduled.Status != models.ScheduledFailed || message != nil {
+		t.Fatalf("got %+v and message %v, want scheduled message %d failed without a message", scheduled, message, id)
+	}
+
+	var messages int
+	if err := env.db.QueryRow(`SELECT COUNT(*) FROM messages WHERE room_id = $1;`, roomID).Scan(&messages); err != nil || messages != 0 {
+		t.Errorf("%d messages stored (%v), want none", messages, err)
+	}
+}
+
+func TestScheduledFirstReplyFollowsThread(t *testing.T) {
+	env := newTestEnv(t)
+	owner, member, other := env.createUser(t), env.createUser(t), env.createUser(t)
+	roomID := env.createRoom(t, owner, member, other)
+	service := NewScheduledMessageService(repository.NewScheduledMessageRepository(env.db), env.rooms)
+
+	root, err := env.rooms.AddMessageToRoom(roomID, owner, "thread", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env.scheduleDue(t, roomID, member, "first reply", root.ID)
+	scheduled, _, firstReply, err := service.ScheduledRepo.DispatchNext()
+	if err != nil || scheduled == nil || !firstReply {
+		t.Fatalf("first scheduled reply: got %v, first reply %t (%v)", scheduled, firstReply, err)
+	}
+
+	env.scheduleDue(t, roomID, other, "second reply", root.ID)
+	scheduled, message, firstReply, err := service.ScheduledRepo.DispatchNext()
+	if err != nil || scheduled == nil || firstReply {
+		t.Fatalf("second scheduled reply: got %v, first reply %t (%v)", scheduled, firstReply, err)
+	}
+	service.RoomService.PublishMessage(message, firstReply)
+
+	// The starter only follows from the first reply on, which was not published
+	if following, err := env.roomRepo.IsFollowingThread(root.ID, owner); err != nil || following {
+		t.Errorf("starter follows the thread after a later reply (%v)", err)
+	}
+
+	// Dispatching a first reply through the service makes the starter follow
+	root2, err := env.rooms.AddMessageToRoom(roomID, owner, "another thread", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.scheduleDue(t, roomID, other, "reply", root2.ID)
+	service.dispatchDue()
+
+	for _, userID := range []int{owner, other} {
+		if following, err := env.roomRepo.IsFollowingThread(root2.ID, userID); err != nil || !following {
+			t.Errorf("user %d does not follow the thread (%v)", userID, err)
+		}
+	}
+	var replies int
+	if err := env.db.QueryRow(`SELECT reply_count FROM messages WHERE id = $1;`, root2.ID).Scan(&replies); err != nil || replies != 1 {
+		t.Errorf("reply_count = %d (%v), want 1", replies, err)
+	}
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"chatingApp/models"
+	"chatingApp/repository"
+	"errors"
+	"log"
+	"strings"
+)
+
+// Search result paging limits.
+const (
+	DefaultSearchPageSize = 20
+	MaxSearchPageSize     = 100
+)
+
+var (
+	// ErrEmptySearchQuery is returned when a search has no terms.
+	ErrEmptySearchQuery = errors.New("search query is empty")
+	// ErrGlobalSearchForbidden is returned when a user who is not a super-admin asks for a global search.
+	ErrGlobalSearchForbidden = errors.New("global search requires super-admin")
+)
+
+// SearchService provides full-text search over messages.
+type SearchService struct {
+	SearchRepo  *repository.SearchRepository
+	Permissions *RoomPermissionService
+}
+
+// NewSearchService creates a new instance of SearchService.
+func NewSearchService(searchRepo *repository.SearchRepository, permissions *RoomPermissionService) *SearchService {
+	return &SearchService{SearchRepo: searchRepo, Permissions: permissions}
+}
+
+// SearchMessages finds the messages matching a query in the rooms the caller belongs to,
+// best match first. Super-admins may search every room with a global query.
+func (s *SearchService) SearchMessages(query models.MessageSearchQuery, callerID int, callerRole string) (*models.MessageSearchResponse, error) {
+	query.Query = strings.TrimSpace(query.Query)
+	if query.Query == "" {
+		return nil, ErrEmptySearchQuery
+	}
+
+	if query.Global && callerRole != "super-admin" {
+		log.Println("❌ Error: Global search requested by a non super-admin")
+		return nil, ErrGlobalSearchForbidden
+	}
+	if !query.Global && query.RoomID > 0 && !s.Permissions.IsMember(query.RoomID, callerID) {
+		log.Println("❌ Error: User is not in the room")
+		return nil, ErrUserNotInRoom
+	}
+
+	if query.Limit <= 0 {
+		query.Limit = DefaultSearchPageSize
+	}
+	if query.Limit > MaxSearchPageSize {
+		query.Limit = MaxSearchPageSize
+	}
+	limit := query.Limit
+
+	// Fetch one extra row to find out whether another page exists
+	query.Limit++
+	results, err := s.SearchRepo.SearchMessages(query, callerID)
+	if err != nil {
+		log.Println("❌ Error: Failed to search messages", err)
+		return nil, err
+	}
+
+	hasMore := len(results) > limit
+	if hasMore {
+		results = results[:limit]
+	}
+	return &models.MessageSearchResponse{Results: results, HasMore: hasMore}, nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoragePathRejectsEscapingKeys(t *testing.T) {
+	s := &LocalStorage{root: "/srv/uploads"}
+
+	for _, key := range []string{"", "/", "..", "../secret", "rooms/../../etc/passwd", "rooms/..", "a/../b", "..\\windows"} {
+		if path, err := s.path(key); err == nil {
+			t.Errorf("path(%q) = %q, want an error", key, path)
+		}
+	}
+}
+
+func TestLocalStoragePath(t *testing.T) {
+	s := &LocalStorage{root: "/srv/uploads"}
+
+	tests := map[string]string{
+		"rooms/1/a.png":  "/srv/uploads/rooms/1/a.png",
+		"/rooms/1/a.png": "/srv/uploads/rooms/1/a.png",
+		"rooms//1/./a":   "/srv/uploads/rooms/1/a",
+		"a..b.txt":       "", // Any ".." is refused, even inside a name
+	}
+	for key, want := range tests {
+		got, err := s.path(key)
+		if want == "" {
+			if err == nil {
+				t.Errorf("path(%q) = %q, want an error", key, got)
+			}
+			continue
+		}
+		if err != nil || got != filepath.FromSlash(want) {
+			t.Errorf("path(%q) = %q, %v, want %q", key, got, err, want)
+		}
+	}
+}
+
+func TestLocalStorageRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewLocalStorage(filepath.Join(dir, "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "rooms/1/file.txt", strings.NewReader("contents and more"), 8, "text/plain"); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	reader, err := s.Get(ctx, "rooms/1/file.txt")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(got) != "contents" {
+		t.Errorf("Get = %q, want %q", got, "contents")
+	}
+
+	// No temporary files are left beside the object
+	entries, err := os.ReadDir(filepath.Join(dir, "uploads", "rooms", "1"))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("directory holds %d entries (%v), want 1", len(entries), err)
+	}
+
+	if err := s.Delete(ctx, "rooms/1/file.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := s.Delete(ctx, "rooms/1/file.txt"); err != nil {
+		t.Errorf("Delete of a missing file = %v, want nil", err)
+	}
+	if _, err := s.Get(ctx, "rooms/1/file.txt"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v, want ErrNotFound", err)
+	}
+
+	if err := s.Put(ctx, "../outside.txt", strings.NewReader("x"), 1, ""); err == nil {
+		t.Error("Put outside the root succeeded")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "outside.txt")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("file written outside the root: %v", err)
+	}
+}